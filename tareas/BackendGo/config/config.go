@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config holds every setting the API needs at startup
+type Config struct {
+	Port            string
+	MongoURI        string
+	DatabaseName    string
+	JWTSecret       string
+	CORSOrigins     []string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	TrashRetention  time.Duration // how long deleted items stay restorable
+	TrashPurgeEvery time.Duration // how often expired items are purged
+	AutoArchiveDays int           // archive tasks completed this many days ago, 0 disables it
+}
+
+// minSecretLength is the minimum accepted length for JWT_SECRET
+const minSecretLength = 32
+
+// Load reads the configuration from the environment. Values from an optional
+// .env file in the working directory are loaded first, without overriding
+// variables that are already set.
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+
+	cfg := &Config{
+		Port:         getEnv("PORT", "8080"),
+		MongoURI:     getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		DatabaseName: getEnv("MONGO_DB", "task_db"),
+		JWTSecret:    os.Getenv("JWT_SECRET"),
+		CORSOrigins:  splitList(getEnv("CORS_ORIGINS", "http://localhost:3000")),
+	}
+
+	var errs []error
+
+	var err error
+	if cfg.AccessTokenTTL, err = getDuration("ACCESS_TOKEN_TTL", 15*time.Minute); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.RefreshTokenTTL, err = getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.TrashRetention, err = getDuration("TRASH_RETENTION", 30*24*time.Hour); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.TrashPurgeEvery, err = getDuration("TRASH_PURGE_INTERVAL", time.Hour); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.AutoArchiveDays, err = getNonNegativeInt("AUTO_ARCHIVE_DAYS", 0); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// Addr returns the listen address for the HTTP server
+func (c *Config) Addr() string {
+	return ":" + c.Port
+}
+
+// validate checks that the loaded values are usable
+func (c *Config) validate() error {
+	var errs []error
+
+	if len(c.JWTSecret) < minSecretLength {
+		errs = append(errs, fmt.Errorf("JWT_SECRET must be set and at least %d characters long", minSecretLength))
+	}
+
+	if _, err := parsePort(c.Port); err != nil {
+		errs = append(errs, err)
+	}
+
+	if !strings.HasPrefix(c.MongoURI, "mongodb://") && !strings.HasPrefix(c.MongoURI, "mongodb+srv://") {
+		errs = append(errs, errors.New("MONGO_URI must start with mongodb:// or mongodb+srv://"))
+	}
+
+	if c.DatabaseName == "" {
+		errs = append(errs, errors.New("MONGO_DB must not be empty"))
+	}
+
+	if len(c.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("CORS_ORIGINS must list at least one origin"))
+	}
+	for _, origin := range c.CORSOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("CORS_ORIGINS contains an invalid origin %q", origin))
+		}
+	}
+
+	if c.RefreshTokenTTL <= c.AccessTokenTTL {
+		errs = append(errs, errors.New("REFRESH_TOKEN_TTL must be longer than ACCESS_TOKEN_TTL"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// getEnv returns the value of key, or def when it is unset or empty
+func getEnv(key, def string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return def
+}
+
+// getDuration parses a duration such as "15m" or "720h" from key
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", key, value)
+	}
+	return d, nil
+}
+
+// getNonNegativeInt parses a whole number that is zero or more from key
+func getNonNegativeInt(key string, def int) (int, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a whole number of zero or more, got %q", key, value)
+	}
+	return n, nil
+}
+
+// parsePort checks that port is a number between 1 and 65535
+func parsePort(port string) (int, error) {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("PORT must be a number between 1 and 65535, got %q", port)
+	}
+	return n, nil
+}
+
+// splitList splits a comma separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
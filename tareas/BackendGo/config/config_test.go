@@ -0,0 +1,133 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// setEnv clears every variable read by Load, then sets the given ones
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range []string{
+		"PORT", "MONGO_URI", "MONGO_DB", "JWT_SECRET", "CORS_ORIGINS",
+		"ACCESS_TOKEN_TTL", "REFRESH_TOKEN_TTL", "TRASH_RETENTION",
+		"TRASH_PURGE_INTERVAL", "AUTO_ARCHIVE_DAYS",
+	} {
+		t.Setenv(key, "")
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setEnv(t, map[string]string{"JWT_SECRET": testSecret})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := &Config{
+		Port:            "8080",
+		MongoURI:        "mongodb://localhost:27017",
+		DatabaseName:    "task_db",
+		JWTSecret:       testSecret,
+		CORSOrigins:     []string{"http://localhost:3000"},
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+		TrashRetention:  30 * 24 * time.Hour,
+		TrashPurgeEvery: time.Hour,
+		AutoArchiveDays: 0,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Load = %+v, want %+v", cfg, want)
+	}
+	if cfg.Addr() != ":8080" {
+		t.Errorf("Addr = %q, want %q", cfg.Addr(), ":8080")
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	setEnv(t, map[string]string{
+		"JWT_SECRET":        testSecret,
+		"PORT":              " 9000 ",
+		"MONGO_URI":         "mongodb+srv://cluster.example.com",
+		"CORS_ORIGINS":      "https://a.example.com, ,https://b.example.com,",
+		"ACCESS_TOKEN_TTL":  "5m",
+		"REFRESH_TOKEN_TTL": "24h",
+		"AUTO_ARCHIVE_DAYS": "7",
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Port != "9000" {
+		t.Errorf("Port = %q, want %q", cfg.Port, "9000")
+	}
+	if want := []string{"https://a.example.com", "https://b.example.com"}; !reflect.DeepEqual(cfg.CORSOrigins, want) {
+		t.Errorf("CORSOrigins = %q, want %q", cfg.CORSOrigins, want)
+	}
+	if cfg.AccessTokenTTL != 5*time.Minute || cfg.RefreshTokenTTL != 24*time.Hour {
+		t.Errorf("token TTLs = %v, %v, want 5m, 24h", cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	}
+	if cfg.AutoArchiveDays != 7 {
+		t.Errorf("AutoArchiveDays = %d, want 7", cfg.AutoArchiveDays)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"missing secret", map[string]string{"JWT_SECRET": ""}, "JWT_SECRET"},
+		{"short secret", map[string]string{"JWT_SECRET": "short"}, "JWT_SECRET"},
+		{"port not a number", map[string]string{"PORT": "http"}, "PORT"},
+		{"port out of range", map[string]string{"PORT": "70000"}, "PORT"},
+		{"mongo scheme", map[string]string{"MONGO_URI": "postgres://localhost"}, "MONGO_URI"},
+		{"origin without scheme", map[string]string{"CORS_ORIGINS": "localhost:3000"}, "CORS_ORIGINS"},
+		{"only empty origins", map[string]string{"CORS_ORIGINS": " , "}, "CORS_ORIGINS"},
+		{"duration not parsed", map[string]string{"ACCESS_TOKEN_TTL": "15"}, "ACCESS_TOKEN_TTL"},
+		{"negative duration", map[string]string{"TRASH_RETENTION": "-1h"}, "TRASH_RETENTION"},
+		{"refresh shorter than access", map[string]string{"ACCESS_TOKEN_TTL": "2h", "REFRESH_TOKEN_TTL": "1h"}, "REFRESH_TOKEN_TTL"},
+		{"negative archive days", map[string]string{"AUTO_ARCHIVE_DAYS": "-1"}, "AUTO_ARCHIVE_DAYS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"JWT_SECRET": testSecret}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			setEnv(t, env)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("Load succeeded, want an error mentioning %s", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %q, want it to mention %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	setEnv(t, map[string]string{"PORT": "0", "MONGO_URI": "localhost"})
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load succeeded, want an error")
+	}
+	for _, key := range []string{"JWT_SECRET", "PORT", "MONGO_URI"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Load error = %q, want it to mention %s", err, key)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-template/middleware"
+	"go-template/models"
+	"go-template/services"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ------------------- Activity history -------------------
+// Every change to a task or its comments is recorded as an activity. Writes
+// made in a transaction record their activities in it; single writes record
+// them afterwards on a best effort basis, logging failures instead of
+// failing a request whose change was already applied. Comment text isn't
+// copied into the history, so it goes away when the comment is purged.
+
+func getActivitiesCollection(c *gin.Context) *mongo.Collection {
+	return services.DB.Collection("activities")
+}
+
+// untrackedTaskFields are task JSON fields left out of activity changes
+// because they change on every write or mirror another field
+var untrackedTaskFields = map[string]bool{
+	"id":         true,
+	"version":    true,
+	"createdAt":  true,
+	"updatedAt":  true,
+	"createdBy":  true,
+	"assignedTo": true, // mirrors assignees
+}
+
+// GetTaskActivity lists the history of a task, newest first. limit sets the
+// page size like in GET /tasks, and cursor takes the X-Next-Cursor header
+// of the previous page, which is the ID of its last activity. The total is
+// sent in X-Total-Count.
+// Requires task:read on the task
+func GetTaskActivity(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{"taskId": task.ID}
+	collection := getActivitiesCollection(c)
+
+	total, err := collection.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count activity"})
+		return
+	}
+
+	// The cursor is the ID of the last activity of the previous page
+	pageFilter := filter
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		pageFilter = bson.M{"taskId": task.ID, "_id": bson.M{"$lt": after}}
+	}
+
+	cursor, err := collection.Find(context.TODO(), pageFilter,
+		options.Find().SetSort(bson.M{"_id": -1}).SetLimit(int64(limit)+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	activities := []models.Activity{}
+	if err := cursor.All(context.TODO(), &activities); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode activity"})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if len(activities) > limit {
+		activities = activities[:limit]
+		c.Header("X-Next-Cursor", activities[limit-1].ID.Hex())
+	}
+
+	c.JSON(http.StatusOK, activities)
+}
+
+// recordActivity stores an activity by the authenticated user after the
+// change was written, logging failures
+func recordActivity(c *gin.Context, activity models.Activity) {
+	if err := recordActivities(context.TODO(), c, []models.Activity{activity}); err != nil {
+		log.Println("Failed to record activity on task", activity.TaskID.Hex(), ":", err)
+	}
+}
+
+// recordActivities stores activities by the authenticated user. Called with
+// a session context, a failure aborts the transaction making the change.
+func recordActivities(ctx context.Context, c *gin.Context, activities []models.Activity) error {
+	actorID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		return err
+	}
+	for i := range activities {
+		activities[i].ActorID = &actorID
+	}
+	return services.RecordActivities(ctx, activities)
+}
+
+// commentActivity is an activity on a comment
+func commentActivity(comment models.Comment, action string) models.Activity {
+	commentID := comment.ID
+	return models.Activity{TaskID: comment.TaskID, Action: action, CommentID: &commentID}
+}
+
+// recordTaskChange records the fields that differ between two versions of a
+// task. Nothing is recorded if no tracked field changed.
+func recordTaskChange(c *gin.Context, action string, before, after models.Task) {
+	changes := diffTasks(before, after)
+	if len(changes) == 0 && action == models.ActivityTaskUpdated {
+		return
+	}
+	recordActivity(c, models.Activity{TaskID: after.ID, Action: action, Changes: changes})
+}
+
+// diffTasks compares two tasks field by field as they appear in JSON
+func diffTasks(before, after models.Task) []models.FieldChange {
+	beforeFields, afterFields := taskFields(before), taskFields(after)
+
+	names := map[string]bool{}
+	for name := range beforeFields {
+		names[name] = true
+	}
+	for name := range afterFields {
+		names[name] = true
+	}
+
+	var changes []models.FieldChange
+	for name := range names {
+		if untrackedTaskFields[name] || reflect.DeepEqual(beforeFields[name], afterFields[name]) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{Field: name, Before: beforeFields[name], After: afterFields[name]})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// taskFields returns the task's JSON fields as plain values
+func taskFields(task models.Task) map[string]interface{} {
+	fields := map[string]interface{}{}
+	data, err := json.Marshal(task)
+	if err == nil {
+		err = json.Unmarshal(data, &fields)
+	}
+	if err != nil {
+		log.Println("Failed to compare task versions:", err)
+	}
+	return fields
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"go-template/middleware"
+	"go-template/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ------------------- Archive -------------------
+// Completed tasks can be archived to hide them from task lists. They stay
+// readable by ID and in search results, and reopening an archived task
+// takes it out of the archive (see models.Task.SetStatus).
+
+// ArchiveTask archives a completed task
+// Requires task:update on the task
+func ArchiveTask(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	if task.Status != models.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only completed tasks can be archived"})
+		return
+	}
+	if task.Archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is already archived"})
+		return
+	}
+
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	updateTaskVersioned(c, task, nil, bson.M{"$set": bson.M{
+		"archived":   true,
+		"archivedAt": primitive.NewDateTimeFromTime(time.Now()),
+	}}, http.StatusOK)
+}
+
+// UnarchiveTask brings an archived task back to the task lists
+// Requires task:update on the task
+func UnarchiveTask(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	if !task.Archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is not archived"})
+		return
+	}
+
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	updateTaskVersioned(c, task, nil, bson.M{"$unset": bson.M{"archived": "", "archivedAt": ""}}, http.StatusOK)
+}
@@ -1,9 +1,13 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 	"go-template/models"
 	"go-template/middleware"
+	"go-template/services"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,16 +18,38 @@ import (
 
 // CreateUser handles user registration by creating a new user account
 func CreateUser(c *gin.Context){
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	// Define the request structure DTO
+	type RegisterRequest struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Avatar   string `json:"avatar"`
+	}
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	if user.Name == "" || user.Email == "" || user.Password == "" {
+	if req.Name == "" || req.Email == "" || req.Password == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Name or email or password is required"})
 		return
 	}
+	if len(req.Password) > services.MaxPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password can't be longer than " + strconv.Itoa(services.MaxPasswordLength) + " bytes"})
+		return
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	user := models.User{
+		Name:      req.Name,
+		Email:     req.Email,
+		Password:  req.Password,
+		Avatar:    req.Avatar,
+		Role:      models.RoleUser,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
 
 	users := getUserCollection(c)
 	if users == nil {
@@ -43,13 +69,22 @@ func CreateUser(c *gin.Context){
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking existing user"})
 		return
 	}
+
+	// Never store the password in plain text
+	hashed, err := services.HashPassword(user.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	user.Password = hashed
+
 	_, err = users.InsertOne(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "user": user})
+	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "user": user.Public()})
 
 }
 
@@ -89,14 +124,168 @@ func LoginUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find user"})
 		return
 	}
-	
-	// Generate JWT token
-	token, err := middleware.GenerateToken(existingUser.ID.Hex())
 
+	ok, needsRehash := services.CheckPassword(existingUser.Password, user.Password)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	// Upgrade legacy plain text passwords (or weaker hashes) transparently
+	if needsRehash {
+		// A failed upgrade doesn't stop the login, it is retried next time
+		hashed, err := services.HashPassword(user.Password)
+		if err == nil {
+			_, err = collection.UpdateOne(c, bson.M{"_id": existingUser.ID}, bson.M{"$set": bson.M{"password": hashed}})
+		}
+		if err != nil {
+			log.Println("Failed to rehash password of user", existingUser.ID.Hex(), ":", err)
+		}
+	}
+
+	// Generate access and refresh tokens
+	// A login starts a new token family
+	tokenID := primitive.NewObjectID()
+	tokens, err := issueTokens(c, existingUser.ID, tokenID, tokenID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RefreshToken exchanges a valid refresh token for a new token pair.
+// The presented refresh token is revoked (rotated) on every use.
+func RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	collection := getRefreshTokenCollection(c)
+	hash := middleware.HashRefreshToken(req.RefreshToken)
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	// Atomically revoke the token so it can only be used once, linking it
+	// to the token that replaces it
+	nextID := primitive.NewObjectID()
+	var stored models.RefreshToken
+	err := collection.FindOneAndUpdate(c, bson.M{
+		"tokenHash": hash,
+		"revokedAt": nil,
+		"expiresAt": bson.M{"$gt": now},
+	}, bson.M{"$set": bson.M{"revokedAt": now, "replacedBy": nextID}}).Decode(&stored)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+			return
+		}
+
+		// A revoked token being presented again means it was stolen: revoke
+		// the rest of its family. Other logins of the user are kept.
+		var reused models.RefreshToken
+		err = collection.FindOne(c, bson.M{"tokenHash": hash, "revokedAt": bson.M{"$ne": nil}}).Decode(&reused)
+		if err == nil {
+			_, err = collection.UpdateMany(c, bson.M{"familyId": reused.Family(), "revokedAt": nil}, bson.M{"$set": bson.M{"revokedAt": now}})
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+			return
+		}
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	tokens, err := issueTokens(c, stored.UserID, nextID, stored.Family())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// LogoutUser revokes the given refresh token, or every token of its owner when "all" is set
+func LogoutUser(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+		All          bool   `json:"all"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	collection := getRefreshTokenCollection(c)
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	var stored models.RefreshToken
+	err := collection.FindOneAndUpdate(c,
+		bson.M{"tokenHash": middleware.HashRefreshToken(req.RefreshToken), "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": now}},
+	).Decode(&stored)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	if err == nil && req.All {
+		_, err = collection.UpdateMany(c, bson.M{"userId": stored.UserID, "revokedAt": nil}, bson.M{"$set": bson.M{"revokedAt": now}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// getRefreshTokenCollection returns the MongoDB refresh_tokens collection
+func getRefreshTokenCollection(c *gin.Context) *mongo.Collection {
+	return services.DB.Collection("refresh_tokens")
+}
+
+// tokenResponse is the token pair returned by login and refresh
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+// issueTokens creates an access token and a persisted refresh token for the
+// user. The refresh token is stored with tokenID in the given family.
+func issueTokens(c *gin.Context, userID, tokenID, familyID primitive.ObjectID) (*tokenResponse, error) {
+	accessToken, err := middleware.GenerateToken(userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := middleware.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := models.RefreshToken{
+		ID:        tokenID,
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: middleware.HashRefreshToken(refreshToken),
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(middleware.RefreshTokenTTL())),
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+	}
+	if _, err := getRefreshTokenCollection(c).InsertOne(c, record); err != nil {
+		return nil, err
+	}
+
+	return &tokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(middleware.AccessTokenTTL().Seconds()),
+	}, nil
 }
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"go-template/middleware"
+	"go-template/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ------------------- Checklist Controller Functions -------------------
+// Checklist items are embedded in the task document, so every change is a
+// versioned update of the task and the response is the updated task.
+
+// AddChecklistItem appends an item to the checklist of a task
+// Requires task:update on the task
+func AddChecklistItem(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	var requestBody struct {
+		Text string `json:"text"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if strings.TrimSpace(requestBody.Text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Checklist item text is required"})
+		return
+	}
+
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	item := models.ChecklistItem{
+		ID:        primitive.NewObjectID(),
+		Text:      requestBody.Text,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	updateTaskVersioned(c, task, nil, bson.M{"$push": bson.M{"checklist": item}}, http.StatusCreated)
+}
+
+// ToggleChecklistItem marks a checklist item as done or not done
+// Requires task:status on the task, so assignees can tick items off
+func ToggleChecklistItem(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	var requestBody struct {
+		Done *bool `json:"done" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Done is required"})
+		return
+	}
+
+	itemID, ok := checklistItemID(c, task)
+	if !ok {
+		return
+	}
+
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	updateTaskVersioned(c, task, bson.M{"checklist._id": itemID},
+		bson.M{"$set": bson.M{"checklist.$.done": *requestBody.Done}}, http.StatusOK)
+}
+
+// RemoveChecklistItem deletes an item from the checklist of a task
+// Requires task:update on the task
+func RemoveChecklistItem(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	itemID, ok := checklistItemID(c, task)
+	if !ok {
+		return
+	}
+
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	updateTaskVersioned(c, task, bson.M{"checklist._id": itemID},
+		bson.M{"$pull": bson.M{"checklist": bson.M{"_id": itemID}}}, http.StatusOK)
+}
+
+// checklistItemID parses :itemId and checks the item belongs to the task
+func checklistItemID(c *gin.Context, task models.Task) (primitive.ObjectID, bool) {
+	itemID, err := primitive.ObjectIDFromHex(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid checklist item ID"})
+		return itemID, false
+	}
+
+	for _, item := range task.Checklist {
+		if item.ID == itemID {
+			return itemID, true
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+	return itemID, false
+}
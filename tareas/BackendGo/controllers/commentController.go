@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"go-template/middleware"
 	"go-template/models"
 	"go-template/services"
 	"net/http"
@@ -11,19 +12,22 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func getCommentsCollection(c *gin.Context) *mongo.Collection {
-	return services.Client.Database("task_db").Collection("comments")
+	return services.DB.Collection("comments")
 }
 
-// CreateComment creates a new comment for a task
+// ------------------- Comment Controller Functions -------------------
+// Comments are threaded one level deep: a reply to a reply joins the thread
+// of the comment at its top. Deleting a comment takes its replies along.
+
+// CreateComment creates a new comment for a task, or a reply when the body
+// has a parentId
+// Requires comment:create on the task (see middleware.RequirePermission)
 func CreateComment(c *gin.Context) {
-	taskID, err := primitive.ObjectIDFromHex(c.Param("id")) // Cambiado de "taskId" a "id"
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
-		return
-	}
+	task := middleware.CurrentTask(c)
 
 	var comment models.Comment
 	if err := c.ShouldBindJSON(&comment); err != nil {
@@ -37,71 +41,68 @@ func CreateComment(c *gin.Context) {
 	}
 
 	// Get authenticated user ID
-	userID := c.MustGet("userID").(string)
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	userObjectID, ok := getAuthUserID(c)
+	if !ok {
 		return
 	}
 
-	// Verify task exists and user has access
-	tasksCollection := getTasksCollection(c)
-	var task models.Task
-	err = tasksCollection.FindOne(context.TODO(), bson.M{
-		"_id":       taskID,
-		"createdBy": userObjectID,
-	}).Decode(&task)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found or no permission"})
-		return
+	// Replies must answer a live comment on the same task
+	if comment.ParentID != nil {
+		var parent models.Comment
+		err := getCommentsCollection(c).FindOne(context.TODO(),
+			andFilters(bson.M{"_id": *comment.ParentID, "taskId": task.ID}, notDeleted()),
+		).Decode(&parent)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The comment being replied to doesn't exist on this task"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check parent comment"})
+			return
+		}
+		if parent.ParentID != nil {
+			comment.ParentID = parent.ParentID
+		}
 	}
 
 	// Set comment values
 	comment.ID = primitive.NewObjectID()
-	comment.TaskID = taskID
+	comment.TaskID = task.ID
 	comment.AuthorID = userObjectID
 	comment.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	comment.EditedAt = nil
+	comment.Revisions = nil
+	comment.Reactions = nil
+	comment.SoftDelete = models.SoftDelete{}
 
 	collection := getCommentsCollection(c)
-	_, err = collection.InsertOne(context.TODO(), comment)
+	_, err := collection.InsertOne(context.TODO(), comment)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, comment)
+	recordActivity(c, commentActivity(comment, models.ActivityCommentCreated))
+
+	c.JSON(http.StatusCreated, comment.View(userObjectID))
 }
 
-// GetCommentsByTask gets all comments for a specific task
+// GetCommentsByTask lists the comments of a task as threads: top-level
+// comments oldest first, each with its replies and reaction counts
+// Requires task:read on the task
 func GetCommentsByTask(c *gin.Context) {
-	taskID, err := primitive.ObjectIDFromHex(c.Param("id")) // Cambiado de "taskId" a "id"
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
-		return
-	}
+	task := middleware.CurrentTask(c)
 
-	// Get authenticated user ID
-	userID := c.MustGet("userID").(string)
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
-	// Verify task exists and user has access
-	tasksCollection := getTasksCollection(c)
-	var task models.Task
-	err = tasksCollection.FindOne(context.TODO(), bson.M{
-		"_id":       taskID,
-		"createdBy": userObjectID,
-	}).Decode(&task)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found or no permission"})
+	userID, ok := getAuthUserID(c)
+	if !ok {
 		return
 	}
 
 	collection := getCommentsCollection(c)
-	cursor, err := collection.Find(context.TODO(), bson.M{"taskId": taskID})
+	cursor, err := collection.Find(context.TODO(),
+		andFilters(bson.M{"taskId": task.ID}, notDeleted()),
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}),
+	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
 		return
@@ -114,47 +115,161 @@ func GetCommentsByTask(c *gin.Context) {
 		return
 	}
 
-	if comments == nil {
-		comments = []models.Comment{}
+	c.JSON(http.StatusOK, commentThreads(comments, userID))
+}
+
+// commentThreads groups comments sorted by date into threads. A reply whose
+// parent isn't among the comments is listed as a thread of its own.
+func commentThreads(comments []models.Comment, userID primitive.ObjectID) []models.CommentThread {
+	threads := []models.CommentThread{}
+	index := map[primitive.ObjectID]int{}
+
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			index[comment.ID] = len(threads)
+			threads = append(threads, models.CommentThread{CommentView: comment.View(userID), Replies: []models.CommentView{}})
+		}
+	}
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			continue
+		}
+		if i, ok := index[*comment.ParentID]; ok {
+			threads[i].Replies = append(threads[i].Replies, comment.View(userID))
+		} else {
+			threads = append(threads, models.CommentThread{CommentView: comment.View(userID), Replies: []models.CommentView{}})
+		}
 	}
 
-	c.JSON(http.StatusOK, comments)
+	return threads
 }
 
-// DeleteComment deletes a comment
-func DeleteComment(c *gin.Context) {
-	commentID, err := primitive.ObjectIDFromHex(c.Param("commentId"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+// UpdateComment replaces the text of a comment. The previous text is kept in
+// the comment's revisions.
+// Requires comment:create on the comment's task; only the author can edit it
+func UpdateComment(c *gin.Context) {
+	comment := middleware.CurrentComment(c)
+
+	var requestBody struct {
+		Text string `json:"text"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	// Get authenticated user ID
-	userID := c.MustGet("userID").(string)
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	if requestBody.Text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment text is required"})
 		return
 	}
 
-	collection := getCommentsCollection(c)
+	userObjectID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != userObjectID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own comments"})
+		return
+	}
 
-	// Only allow deletion of own comments
-	filter := bson.M{
-		"_id":      commentID,
-		"authorId": userObjectID,
+	if requestBody.Text == comment.Text {
+		c.JSON(http.StatusOK, comment.View(userObjectID))
+		return
 	}
 
-	result, err := collection.DeleteOne(context.TODO(), filter)
+	// The replaced text was written when the comment was created or last edited
+	written := comment.CreatedAt
+	if comment.EditedAt != nil {
+		written = *comment.EditedAt
+	}
+	revision := models.CommentRevision{Text: comment.Text, CreatedAt: written}
+
+	// Matching on the loaded text keeps a concurrent edit from being lost
+	// without leaving its text in the revisions
+	var updated models.Comment
+	err := getCommentsCollection(c).FindOneAndUpdate(context.TODO(),
+		andFilters(bson.M{"_id": comment.ID, "text": comment.Text}, notDeleted()),
+		bson.M{
+			"$set":  bson.M{"text": requestBody.Text, "editedAt": primitive.NewDateTimeFromTime(time.Now())},
+			"$push": bson.M{"revisions": revision},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "Comment was changed or deleted, reload it and try again"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
 		return
 	}
 
-	if result.DeletedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found or no permission"})
+	recordActivity(c, commentActivity(comment, models.ActivityCommentUpdated))
+
+	c.JSON(http.StatusOK, updated.View(userObjectID))
+}
+
+// DeleteComment moves a comment to the trash
+// Requires comment:create on the comment's task; authors can delete their own
+// comments and roles with comment:delete can delete anyone's
+func DeleteComment(c *gin.Context) {
+	comment := middleware.CurrentComment(c)
+
+	// Get authenticated user ID
+	userObjectID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != userObjectID && !middleware.HasPermission(c, models.PermCommentDelete) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own comments"})
+		return
+	}
+
+	collection := getCommentsCollection(c)
+
+	// Comments go to the trash and can be restored until they are purged.
+	// Replies go along with the comment and come back when it is restored.
+	var replies int
+	err := services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		now := primitive.NewDateTimeFromTime(time.Now())
+		result, err := collection.UpdateOne(sc,
+			andFilters(bson.M{"_id": comment.ID}, notDeleted()),
+			bson.M{"$set": bson.M{"deletedAt": now, "deletedBy": userObjectID}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		repliesFilter := andFilters(bson.M{"parentId": comment.ID}, notDeleted())
+		activities, err := findCommentActivities(sc, c, repliesFilter, models.ActivityCommentDeleted)
+		if err != nil {
+			return err
+		}
+		replies = len(activities)
+
+		_, err = collection.UpdateMany(sc, repliesFilter,
+			bson.M{"$set": bson.M{"deletedAt": now, "deletedBy": userObjectID, "deletedWith": comment.ID}},
+		)
+		if err != nil {
+			return err
+		}
+
+		activities = append([]models.Activity{commentActivity(comment, models.ActivityCommentDeleted)}, activities...)
+		return recordActivities(sc, c, activities)
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully", "replies": replies})
 }
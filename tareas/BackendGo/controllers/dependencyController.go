@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-template/middleware"
+	"go-template/models"
+	"go-template/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ------------------- Dependency Controller Functions -------------------
+// A dependency is stored on the blocked task, in its blockedBy list. The
+// reverse "blocks" relationship is found by querying that list. Tasks can
+// only depend on tasks of the same project, or on personal tasks of the
+// same creator, so every graph is contained in a single project.
+
+// taskSummaryProjection selects the fields of models.TaskSummary
+var taskSummaryProjection = bson.M{"title": 1, "status": 1}
+
+// GetTaskDependencies lists the tasks blocking the task in :id and the tasks
+// it blocks. Only tasks the user can read are listed.
+// Requires task:read on the task
+func GetTaskDependencies(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	access, ok := getTaskAccess(c)
+	if !ok {
+		return
+	}
+	visible := access.visible()
+
+	dependencies := models.TaskDependencies{}
+	var err error
+
+	dependencies.BlockedBy, err = findTaskSummaries(c, andFilters(bson.M{"_id": bson.M{"$in": task.BlockedBy}}, notDeleted(), visible))
+	if err == nil {
+		dependencies.Blocks, err = findTaskSummaries(c, andFilters(bson.M{"blockedBy": task.ID}, notDeleted(), visible))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dependencies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dependencies)
+}
+
+// AddTaskDependency marks the task in :id as blocked by another task
+// Requires task:update on the blocked task
+func AddTaskDependency(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	var requestBody struct {
+		BlockedBy primitive.ObjectID `json:"blockedBy" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil || requestBody.BlockedBy.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "blockedBy must be a task ID"})
+		return
+	}
+
+	if requestBody.BlockedBy == task.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A task can't block itself"})
+		return
+	}
+
+	var blocker models.Task
+	err := getTasksCollection(c).FindOne(context.TODO(), andFilters(bson.M{"_id": requestBody.BlockedBy}, notDeleted())).Decode(&blocker)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blocking task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve blocking task"})
+		return
+	}
+
+	// Keep dependencies inside one project so nobody can link to tasks they can't see
+	if !blocker.SameProject(task) || (task.ProjectID == nil && blocker.CreatedBy != task.CreatedBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tasks can only depend on tasks of the same project"})
+		return
+	}
+
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	// Dependencies of the same graph are added one at a time, so two
+	// requests can't each add half of a cycle without seeing the other
+	var updated models.Task
+	err = services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		if err := services.LockInTransaction(sc, dependencyLock(task)); err != nil {
+			return err
+		}
+
+		cycle, err := createsCycle(sc, c, task.ID, blocker.ID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return errDependencyCycle
+		}
+
+		updated, err = applyTaskUpdate(sc, c, task, nil, bson.M{"$addToSet": bson.M{"blockedBy": blocker.ID}})
+		return err
+	})
+	if errors.Is(err, errDependencyCycle) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This dependency would create a cycle"})
+		return
+	}
+
+	writeTaskUpdate(c, task, updated, err, http.StatusOK)
+}
+
+// errDependencyCycle aborts adding a dependency that would create a cycle
+var errDependencyCycle = errors.New("dependency cycle")
+
+// dependencyLock names the lock serializing dependency changes in the graph
+// of the task: its project's, or its creator's personal tasks
+func dependencyLock(task models.Task) string {
+	if task.ProjectID != nil {
+		return "dependencies:project:" + task.ProjectID.Hex()
+	}
+	return "dependencies:user:" + task.CreatedBy.Hex()
+}
+
+// RemoveTaskDependency removes a blocking task from the task in :id
+// Requires task:update on the blocked task
+func RemoveTaskDependency(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	blockerID, err := primitive.ObjectIDFromHex(c.Param("blockerId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	linked := false
+	for _, id := range task.BlockedBy {
+		if id == blockerID {
+			linked = true
+			break
+		}
+	}
+	if !linked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dependency not found"})
+		return
+	}
+
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	updateTaskVersioned(c, task, nil, bson.M{"$pull": bson.M{"blockedBy": blockerID}}, http.StatusOK)
+}
+
+// GetProjectDependencyGraph returns every task of a project as a node and
+// every dependency between them as an edge from the blocking task
+// Requires project:read
+func GetProjectDependencyGraph(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+
+	cursor, err := getTasksCollection(c).Find(context.TODO(),
+		andFilters(bson.M{"projectId": project.ID}, notDeleted()),
+		options.Find().SetProjection(bson.M{"title": 1, "status": 1, "blockedBy": 1}).SetSort(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var tasks []models.Task
+	if err := cursor.All(context.TODO(), &tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode tasks"})
+		return
+	}
+
+	graph := models.DependencyGraph{
+		Nodes: make([]models.TaskSummary, 0, len(tasks)),
+		Edges: []models.DependencyEdge{},
+	}
+	live := map[primitive.ObjectID]bool{}
+	for _, task := range tasks {
+		live[task.ID] = true
+		graph.Nodes = append(graph.Nodes, models.TaskSummary{ID: task.ID, Title: task.Title, Status: task.Status})
+	}
+	for _, task := range tasks {
+		for _, blocker := range task.BlockedBy {
+			// Deleted blockers stay linked until purged but aren't shown
+			if live[blocker] {
+				graph.Edges = append(graph.Edges, models.DependencyEdge{From: blocker, To: task.ID})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, graph)
+}
+
+// createsCycle reports whether making taskID blocked by blockerID would
+// close a cycle, that is, whether blockerID already depends on taskID
+// directly or through other tasks
+func createsCycle(ctx context.Context, c *gin.Context, taskID, blockerID primitive.ObjectID) (bool, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"_id": blockerID}},
+		bson.M{"$graphLookup": bson.M{
+			"from":             "tasks",
+			"startWith":        "$blockedBy",
+			"connectFromField": "blockedBy",
+			"connectToField":   "_id",
+			"as":               "blockers",
+		}},
+		bson.M{"$match": bson.M{"blockers._id": taskID}},
+		bson.M{"$project": bson.M{"_id": 1}},
+	}
+
+	cursor, err := getTasksCollection(c).Aggregate(ctx, pipeline)
+	if err != nil {
+		return false, err
+	}
+	defer cursor.Close(ctx)
+
+	found := cursor.Next(ctx)
+	return found, cursor.Err()
+}
+
+// checkBlockersDone rejects completing a task while any of the tasks
+// blocking it is still open. Blockers in the trash don't count.
+func checkBlockersDone(c *gin.Context, task models.Task, status models.TaskStatus) bool {
+	if status != models.StatusCompleted || task.Status == models.StatusCompleted || len(task.BlockedBy) == 0 {
+		return true
+	}
+
+	open, err := findTaskSummaries(c, andFilters(bson.M{
+		"_id":    bson.M{"$in": task.BlockedBy},
+		"status": bson.M{"$ne": models.StatusCompleted},
+	}, notDeleted()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check dependencies"})
+		return false
+	}
+	if len(open) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is blocked by tasks that aren't completed", "blockedBy": open})
+		return false
+	}
+	return true
+}
+
+// findTaskSummaries returns the matching tasks in their short form
+func findTaskSummaries(c *gin.Context, filter bson.M) ([]models.TaskSummary, error) {
+	cursor, err := getTasksCollection(c).Find(context.TODO(), filter,
+		options.Find().SetProjection(taskSummaryProjection).SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	summaries := []models.TaskSummary{}
+	if err := cursor.All(context.TODO(), &summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"go-template/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ------------------- Optimistic concurrency -------------------
+// Tasks carry a version counter that is bumped on every write. It is sent
+// to clients as an ETag and checked against If-Match on updates so two
+// people editing the same task can't silently overwrite each other.
+
+// taskETag formats a task version as a strong ETag
+func taskETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// setTaskETag adds the ETag header for the task to the response
+func setTaskETag(c *gin.Context, task models.Task) {
+	c.Header("ETag", taskETag(task.Version))
+}
+
+// checkIfMatch compares the If-Match header with the task's current version.
+// A missing header or "*" always matches. If-Match uses strong comparison,
+// so weak (W/) tags never match. On mismatch it writes a 412 with the
+// current version and returns false.
+func checkIfMatch(c *gin.Context, task models.Task) bool {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+
+	current := taskETag(task.Version)
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == current {
+			return true
+		}
+	}
+
+	preconditionFailed(c, task.Version)
+	return false
+}
+
+// preconditionFailed writes the 412 response for a stale task version
+func preconditionFailed(c *gin.Context, currentVersion int64) {
+	c.Header("ETag", taskETag(currentVersion))
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "Task was modified by someone else, reload it and try again",
+		"version": currentVersion,
+	})
+}
+
+// updateConflict is called when a versioned update matched no document. It
+// reloads the task to tell a deleted task (404) from a concurrent edit (412).
+func updateConflict(c *gin.Context, taskID primitive.ObjectID) {
+	var task models.Task
+	err := getTasksCollection(c).FindOne(context.TODO(), andFilters(bson.M{"_id": taskID}, notDeleted())).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve task"})
+		return
+	}
+	preconditionFailed(c, task.Version)
+}
+
+// versionFilter matches a task at the given version. Tasks created before
+// versioning have no version field and are treated as version 0.
+func versionFilter(version int64) bson.M {
+	if version == 0 {
+		return bson.M{"version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"version": version}
+}
+
+// updateTaskVersioned applies an update to the task if it is still at the
+// version that was loaded, bumping the version, and writes the updated task
+// with the given status. Used by endpoints that change embedded task fields.
+func updateTaskVersioned(c *gin.Context, task models.Task, filter bson.M, update bson.M, status int) {
+	updated, err := applyTaskUpdate(context.TODO(), c, task, filter, update)
+	writeTaskUpdate(c, task, updated, err, status)
+}
+
+// applyTaskUpdate is the write done by updateTaskVersioned, for callers that
+// run it inside a transaction. It returns mongo.ErrNoDocuments if the task
+// changed since it was loaded.
+func applyTaskUpdate(ctx context.Context, c *gin.Context, task models.Task, filter bson.M, update bson.M) (models.Task, error) {
+	update["$inc"] = bson.M{"version": 1}
+	if set, ok := update["$set"].(bson.M); ok {
+		set["updatedAt"] = primitive.NewDateTimeFromTime(time.Now())
+	} else {
+		update["$set"] = bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())}
+	}
+
+	var updated models.Task
+	err := getTasksCollection(c).FindOneAndUpdate(ctx,
+		andFilters(bson.M{"_id": task.ID}, versionFilter(task.Version), filter),
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	return updated, err
+}
+
+// writeTaskUpdate responds to an update made with applyTaskUpdate
+func writeTaskUpdate(c *gin.Context, task, updated models.Task, err error, status int) {
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			updateConflict(c, task.ID)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	recordTaskChange(c, models.ActivityTaskUpdated, task, updated)
+
+	setTaskETag(c, updated)
+	c.JSON(status, taskResult(updated))
+}
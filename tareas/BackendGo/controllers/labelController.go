@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"go-template/middleware"
+	"go-template/models"
+	"go-template/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ------------------- Label Controller Functions -------------------
+// The label catalog is embedded in the project. Tasks store label names, so
+// renaming or deleting a label also updates the project's tasks.
+
+// GetProjectLabels lists the label catalog of a project
+// Requires project:read
+func GetProjectLabels(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+
+	labels := project.Labels
+	if labels == nil {
+		labels = []models.Label{}
+	}
+	c.JSON(http.StatusOK, labels)
+}
+
+// CreateProjectLabel adds a label to the catalog of a project
+// Requires project:update
+func CreateProjectLabel(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+
+	var label models.Label
+	if err := c.ShouldBindJSON(&label); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := label.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The name filter keeps two requests from adding the same label
+	result, err := getProjectsCollection(c).UpdateOne(context.TODO(),
+		bson.M{"_id": project.ID, "labels.name": bson.M{"$ne": label.Name}},
+		bson.M{
+			"$push": bson.M{"labels": label},
+			"$set":  bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create label"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Label already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, label)
+}
+
+// UpdateProjectLabel renames or recolors a label. Renames are applied to
+// every task of the project that has the label.
+// Requires project:update
+func UpdateProjectLabel(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+
+	name := models.NormalizeLabelName(c.Param("name"))
+	var label models.Label
+	for _, l := range project.Labels {
+		if l.Name == name {
+			label = l
+		}
+	}
+	if label.Name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return
+	}
+
+	var requestBody struct {
+		Name  *string `json:"name"`
+		Color *string `json:"color"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if requestBody.Name != nil {
+		label.Name = *requestBody.Name
+	}
+	if requestBody.Color != nil {
+		label.Color = *requestBody.Color
+	}
+	if err := label.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{"_id": project.ID, "labels.name": name}
+	if label.Name != name {
+		// The new name must not be taken by another label
+		filter = andFilters(filter, bson.M{"labels.name": bson.M{"$ne": label.Name}})
+	}
+
+	// The catalog and the tasks change together so no task keeps a label
+	// the catalog no longer has
+	err := services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		result, err := getProjectsCollection(c).UpdateOne(sc, filter,
+			bson.M{"$set": bson.M{
+				"labels.$[label]": label,
+				"updatedAt":       primitive.NewDateTimeFromTime(time.Now()),
+			}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: bson.A{bson.M{"label.name": name}}}),
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		if label.Name != name {
+			return replaceTaskLabel(c, sc, project.ID, name, bson.A{label.Name})
+		}
+		return nil
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "Label was changed or the new name is already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update label"})
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+// DeleteProjectLabel removes a label from the catalog and from the project's tasks
+// Requires project:update
+func DeleteProjectLabel(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+
+	name := models.NormalizeLabelName(c.Param("name"))
+	err := services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		result, err := getProjectsCollection(c).UpdateOne(sc,
+			bson.M{"_id": project.ID, "labels.name": name},
+			bson.M{
+				"$pull": bson.M{"labels": bson.M{"name": name}},
+				"$set":  bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		return replaceTaskLabel(c, sc, project.ID, name, bson.A{})
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete label"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Label deleted successfully"})
+}
+
+// replaceTaskLabel replaces a label with the given ones (none to remove it)
+// on every task of the project, bumping their version and recording the
+// change in their history. It runs inside the transaction that changes the
+// catalog.
+func replaceTaskLabel(c *gin.Context, sc mongo.SessionContext, projectID primitive.ObjectID, name string, replacement bson.A) error {
+	tasks := getTasksCollection(c)
+	filter := bson.M{"projectId": projectID, "labels": name}
+	labelsOnly := options.Find().SetProjection(bson.M{"labels": 1})
+
+	cursor, err := tasks.Find(sc, filter, labelsOnly)
+	if err != nil {
+		return err
+	}
+	var before []models.Task
+	if err := cursor.All(sc, &before); err != nil {
+		return err
+	}
+	if len(before) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(before))
+	for _, task := range before {
+		ids = append(ids, task.ID)
+	}
+
+	_, err = tasks.UpdateMany(sc, filter,
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"labels":    bson.M{"$setUnion": bson.A{bson.M{"$setDifference": bson.A{"$labels", bson.A{name}}}, replacement}},
+			"version":   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+			"updatedAt": "$$NOW",
+		}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Read the new labels back, the transaction sees its own writes
+	cursor, err = tasks.Find(sc, bson.M{"_id": bson.M{"$in": ids}}, labelsOnly)
+	if err != nil {
+		return err
+	}
+	var after []models.Task
+	if err := cursor.All(sc, &after); err != nil {
+		return err
+	}
+	labels := map[primitive.ObjectID][]string{}
+	for _, task := range after {
+		labels[task.ID] = task.Labels
+	}
+
+	activities := make([]models.Activity, 0, len(before))
+	for _, task := range before {
+		changed := task
+		changed.Labels = labels[task.ID]
+		activities = append(activities, models.Activity{
+			TaskID:  task.ID,
+			Action:  models.ActivityTaskUpdated,
+			Changes: diffTasks(task, changed),
+		})
+	}
+	return recordActivities(sc, c, activities)
+}
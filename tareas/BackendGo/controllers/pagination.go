@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"go-template/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ------------------- Task list paging -------------------
+// GET /tasks pages with an opaque cursor that encodes the sort value and _id
+// of the last returned task. Because _id breaks ties the order is total, so
+// pages never skip or repeat tasks even when new ones are inserted. Tasks
+// are sorted and sought on stored fields so the {scope, field, _id} indexes
+// serve each page. Text search results ordered by relevance page by offset
+// instead, since scores aren't stable across queries.
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+
+	// sortRelevance orders text search results by score
+	sortRelevance = "relevance"
+
+	maxSearchLength = 200
+)
+
+// sortableTaskFields maps the sort query values to the stored task fields
+// they order by. Priorities sort by rank so "alta" comes above "media" and
+// "baja" instead of alphabetically.
+var sortableTaskFields = map[string]string{
+	"createdAt": "createdAt",
+	"updatedAt": "updatedAt",
+	"priority":  "priorityRank",
+	"dueDate":   "dueDate",
+}
+
+// taskListOptions holds the parsed paging parameters of GET /tasks
+type taskListOptions struct {
+	Limit     int
+	Page      int
+	SortField string
+	SortDir   int
+	After     *taskCursor
+	Search    string
+}
+
+// taskCursor is the position after which the next page starts
+type taskCursor struct {
+	Sort   string             `json:"s"`
+	Dir    int                `json:"d"`
+	Value  *int64             `json:"v"` // nil when the sort field is missing
+	ID     primitive.ObjectID `json:"id"`
+	Offset int64              `json:"o,omitempty"` // used by relevance ordering
+}
+
+// parseTaskListOptions reads limit, page, cursor, sort and q from the query string
+func parseTaskListOptions(c *gin.Context) (taskListOptions, error) {
+	opts := taskListOptions{Limit: defaultPageSize, SortField: "createdAt", SortDir: -1}
+
+	search, err := parseSearchQuery(c.Query("q"), false)
+	if err != nil {
+		return opts, err
+	}
+	opts.Search = search
+	if search != "" {
+		// Best matches first unless another order is requested
+		opts.SortField = sortRelevance
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		return opts, err
+	}
+	opts.Limit = limit
+
+	if sort := c.Query("sort"); sort != "" {
+		dir := 1
+		if strings.HasPrefix(sort, "-") {
+			dir = -1
+			sort = sort[1:]
+		}
+		if sort == sortRelevance && opts.Search != "" {
+			dir = -1
+		} else if _, ok := sortableTaskFields[sort]; !ok {
+			return opts, errors.New("Invalid sort. Valid values: createdAt, updatedAt, priority, dueDate, relevance with q (prefix with - for descending)")
+		}
+		opts.SortField = sort
+		opts.SortDir = dir
+	}
+
+	cursor := c.Query("cursor")
+	page := c.Query("page")
+	if cursor != "" && page != "" {
+		return opts, errors.New("Use either cursor or page, not both")
+	}
+
+	if page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return opts, errors.New("Invalid page. Must be a positive number")
+		}
+		opts.Page = n
+	}
+
+	if cursor != "" {
+		after, err := decodeTaskCursor(cursor)
+		if err != nil || after.Sort != opts.SortField || after.Dir != opts.SortDir {
+			return opts, errors.New("Invalid cursor")
+		}
+		opts.After = after
+	}
+
+	return opts, nil
+}
+
+// parseLimit reads the page size from the limit query parameter
+func parseLimit(c *gin.Context) (int, error) {
+	limit := c.Query("limit")
+	if limit == "" {
+		return defaultPageSize, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 || n > maxPageSize {
+		return 0, errors.New("Invalid limit. Must be between 1 and " + strconv.Itoa(maxPageSize))
+	}
+	return n, nil
+}
+
+// parseSearchQuery validates a full-text search string
+func parseSearchQuery(q string, required bool) (string, error) {
+	q = strings.TrimSpace(q)
+	if q == "" && required {
+		return "", errors.New("Search query q is required")
+	}
+	if len(q) > maxSearchLength {
+		return "", errors.New("Search query is too long. Max " + strconv.Itoa(maxSearchLength) + " characters")
+	}
+	return q, nil
+}
+
+// encodeTaskCursor serializes a cursor as URL safe base64
+func encodeTaskCursor(cursor taskCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTaskCursor parses a cursor produced by encodeTaskCursor
+func decodeTaskCursor(value string) (*taskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID.IsZero() {
+		return nil, errors.New("cursor without id")
+	}
+	return &cursor, nil
+}
+
+// afterCursorFilter matches the tasks that come after the cursor in the
+// requested order. Missing sort values sort first ascending and last
+// descending, the same way MongoDB orders nulls.
+func afterCursorFilter(cursor *taskCursor) bson.M {
+	field := sortableTaskFields[cursor.Sort]
+	op := "$gt"
+	if cursor.Dir < 0 {
+		op = "$lt"
+	}
+
+	if cursor.Value == nil {
+		sameNull := bson.M{field: nil, "_id": bson.M{op: cursor.ID}}
+		if cursor.Dir > 0 {
+			return bson.M{"$or": bson.A{sameNull, bson.M{field: bson.M{"$ne": nil}}}}
+		}
+		return sameNull
+	}
+
+	value := cursorValue(cursor.Sort, *cursor.Value)
+	conditions := bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: cursor.ID}},
+	}
+	if cursor.Dir < 0 {
+		conditions = append(conditions, bson.M{field: nil})
+	}
+	return bson.M{"$or": conditions}
+}
+
+// cursorValue converts the stored cursor number back to the sort key type
+func cursorValue(field string, value int64) interface{} {
+	if field == "priority" {
+		return value
+	}
+	return primitive.DateTime(value)
+}
+
+// taskListPipeline builds the aggregation that returns one page of tasks. It
+// fetches one extra document so the caller can tell whether a next page
+// exists. The cursor is applied in the first $match and the sort is on the
+// stored field so both can use an index.
+func taskListPipeline(filter bson.M, opts taskListOptions) mongo.Pipeline {
+	var sort bson.D
+	if opts.SortField == sortRelevance {
+		sort = bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}}
+	} else {
+		if opts.After != nil {
+			filter = andFilters(filter, afterCursorFilter(opts.After))
+		}
+		sort = bson.D{{Key: sortableTaskFields[opts.SortField], Value: opts.SortDir}, {Key: "_id", Value: opts.SortDir}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: sort}},
+	}
+
+	if skip := pageOffset(opts); skip > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: skip}})
+	}
+
+	pipeline = append(pipeline, bson.D{{Key: "$limit", Value: opts.Limit + 1}})
+
+	if opts.Search != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}})
+	}
+
+	// Count the subtasks of the page's tasks for their progress
+	return append(pipeline, bson.D{{Key: "$lookup", Value: bson.M{
+		"from": "tasks",
+		"let":  bson.M{"taskId": "$_id"},
+		"pipeline": append(bson.A{
+			bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$parentId", "$$taskId"}}}},
+		}, subtaskCountStages()...),
+		"as": subtasksField,
+	}}})
+}
+
+// pageOffset returns how many sorted tasks precede the requested page
+func pageOffset(opts taskListOptions) int64 {
+	if opts.After != nil {
+		return opts.After.Offset
+	}
+	if opts.Page > 1 {
+		return int64(opts.Page-1) * int64(opts.Limit)
+	}
+	return 0
+}
+
+// subtasksField holds the subtask counts joined by taskListPipeline
+const subtasksField = "_subtasks"
+
+// taskSubtasks is the subtask count joined to a task by taskListPipeline
+type taskSubtasks struct {
+	Counts []models.SubtaskCount `bson:"_subtasks"`
+}
+
+// nextTaskCursor builds the cursor pointing after the given document
+func nextTaskCursor(last bson.Raw, opts taskListOptions) (string, error) {
+	id, ok := last.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return "", errors.New("task without _id")
+	}
+	cursor := taskCursor{Sort: opts.SortField, Dir: opts.SortDir, ID: id}
+	if opts.SortField == sortRelevance {
+		cursor.Offset = pageOffset(opts) + int64(opts.Limit)
+		return encodeTaskCursor(cursor), nil
+	}
+
+	value, err := last.LookupErr(sortableTaskFields[opts.SortField])
+	if err == nil {
+		if v, ok := value.DateTimeOK(); ok {
+			cursor.Value = &v
+		} else if v, ok := value.AsInt64OK(); ok {
+			cursor.Value = &v
+		}
+	}
+	return encodeTaskCursor(cursor), nil
+}
+
+// decodeTaskPage decodes the documents returned by taskListPipeline and
+// returns the tasks of the page plus the cursor of the next one, if any
+func decodeTaskPage(docs []bson.Raw, opts taskListOptions) ([]models.TaskResult, string, error) {
+	nextCursor := ""
+	if len(docs) > opts.Limit {
+		docs = docs[:opts.Limit]
+
+		var err error
+		nextCursor, err = nextTaskCursor(docs[len(docs)-1], opts)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	now := time.Now()
+	tasks := make([]models.TaskResult, 0, len(docs))
+	for _, doc := range docs {
+		var task models.TaskResult
+		if err := bson.Unmarshal(doc, &task); err != nil {
+			return nil, "", err
+		}
+		if opts.Search != "" {
+			task.Highlights = highlightTask(task.Task, opts.Search)
+		}
+		task.IsOverdue = task.Task.IsOverdue(now)
+
+		var subtasks taskSubtasks
+		if err := bson.Unmarshal(doc, &subtasks); err != nil {
+			return nil, "", err
+		}
+		count := models.SubtaskCount{}
+		if len(subtasks.Counts) > 0 {
+			count = subtasks.Counts[0]
+		}
+		task.Progress = task.Task.Progress(count)
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nextCursor, nil
+}
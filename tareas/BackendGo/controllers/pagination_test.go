@@ -0,0 +1,319 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"go-template/models"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sortedDoc is a task reduced to the sort key and _id. A nil value is a
+// missing or null field.
+type sortedDoc struct {
+	ID    primitive.ObjectID
+	Value interface{}
+}
+
+// sortKey returns the value as an int64 for comparison
+func sortKey(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case primitive.DateTime:
+		return int64(v)
+	}
+	panic(fmt.Sprintf("unexpected sort value %T", value))
+}
+
+// compareDocs orders two documents the way MongoDB sorts them ascending:
+// nulls first, then by value, then by _id
+func compareDocs(a, b sortedDoc) int {
+	switch {
+	case a.Value == nil && b.Value != nil:
+		return -1
+	case a.Value != nil && b.Value == nil:
+		return 1
+	case a.Value != nil && sortKey(a.Value) != sortKey(b.Value):
+		if sortKey(a.Value) < sortKey(b.Value) {
+			return -1
+		}
+		return 1
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+// compareValues compares a document value with a filter operand
+func compareValues(value, operand interface{}) int {
+	if id, ok := value.(primitive.ObjectID); ok {
+		other := operand.(primitive.ObjectID)
+		return bytes.Compare(id[:], other[:])
+	}
+	a, b := sortKey(value), sortKey(operand)
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// matches evaluates the subset of query operators used by afterCursorFilter
+func matches(doc sortedDoc, field string, filter bson.M) bool {
+	for key, condition := range filter {
+		if key == "$or" {
+			matched := false
+			for _, sub := range condition.(bson.A) {
+				matched = matched || matches(doc, field, sub.(bson.M))
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+
+		var value interface{}
+		switch key {
+		case "_id":
+			value = doc.ID
+		case field:
+			value = doc.Value
+		default:
+			panic("unexpected field " + key)
+		}
+
+		ops, ok := condition.(bson.M)
+		if !ok {
+			// Equality, where nil matches missing fields
+			if condition == nil {
+				if value != nil {
+					return false
+				}
+			} else if value == nil || compareValues(value, condition) != 0 {
+				return false
+			}
+			continue
+		}
+		for op, operand := range ops {
+			var ok bool
+			switch op {
+			case "$ne":
+				ok = value != nil
+				if operand != nil {
+					panic("unexpected $ne operand")
+				}
+			case "$gt":
+				// Range operators never match missing fields
+				ok = value != nil && compareValues(value, operand) > 0
+			case "$lt":
+				ok = value != nil && compareValues(value, operand) < 0
+			default:
+				panic("unexpected operator " + op)
+			}
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestAfterCursorFilter(t *testing.T) {
+	ids := make([]primitive.ObjectID, 8)
+	for i := range ids {
+		ids[i][11] = byte(i + 1)
+	}
+
+	tests := []struct {
+		sort   string
+		values []interface{}
+	}{
+		{"priority", []interface{}{int64(2), nil, int64(3), int64(2), int64(1), nil, int64(3), int64(2)}},
+		{"dueDate", []interface{}{
+			primitive.DateTime(3000), nil, primitive.DateTime(1000), primitive.DateTime(3000),
+			nil, primitive.DateTime(2000), primitive.DateTime(1000), nil,
+		}},
+		{"createdAt", []interface{}{
+			primitive.DateTime(5), primitive.DateTime(5), primitive.DateTime(5), primitive.DateTime(1),
+			primitive.DateTime(9), primitive.DateTime(7), primitive.DateTime(5), primitive.DateTime(2),
+		}},
+	}
+	for _, tt := range tests {
+		for _, dir := range []int{1, -1} {
+			t.Run(fmt.Sprintf("%s/%d", tt.sort, dir), func(t *testing.T) {
+				docs := make([]sortedDoc, len(ids))
+				for i, id := range ids {
+					docs[i] = sortedDoc{ID: id, Value: tt.values[i]}
+				}
+				sort.Slice(docs, func(i, j int) bool {
+					return compareDocs(docs[i], docs[j])*dir < 0
+				})
+
+				field := sortableTaskFields[tt.sort]
+				for i, last := range docs {
+					cursor := &taskCursor{Sort: tt.sort, Dir: dir, ID: last.ID}
+					if last.Value != nil {
+						v := sortKey(last.Value)
+						cursor.Value = &v
+					}
+					filter := afterCursorFilter(cursor)
+
+					var got []primitive.ObjectID
+					for _, doc := range docs {
+						if matches(doc, field, filter) {
+							got = append(got, doc.ID)
+						}
+					}
+					var want []primitive.ObjectID
+					for _, doc := range docs[i+1:] {
+						want = append(want, doc.ID)
+					}
+					if fmt.Sprint(got) != fmt.Sprint(want) {
+						t.Errorf("after %v (%v): got %v, want %v", last.ID, last.Value, got, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNextTaskCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	due := primitive.DateTime(1700000000000)
+
+	tests := []struct {
+		name string
+		doc  bson.M
+		opts taskListOptions
+		want *int64
+	}{
+		{"date", bson.M{"_id": id, "dueDate": due}, taskListOptions{Limit: 10, SortField: "dueDate", SortDir: 1}, ptr(int64(due))},
+		{"missing date", bson.M{"_id": id}, taskListOptions{Limit: 10, SortField: "dueDate", SortDir: -1}, nil},
+		{"null date", bson.M{"_id": id, "dueDate": nil}, taskListOptions{Limit: 10, SortField: "dueDate", SortDir: 1}, nil},
+		{"priority rank stored as int32", bson.M{"_id": id, "priorityRank": int32(3)}, taskListOptions{Limit: 10, SortField: "priority", SortDir: -1}, ptr(int64(3))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := bson.Marshal(tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := nextTaskCursor(raw, tt.opts)
+			if err != nil {
+				t.Fatalf("nextTaskCursor: %v", err)
+			}
+			cursor, err := decodeTaskCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeTaskCursor: %v", err)
+			}
+			if cursor.ID != id || cursor.Sort != tt.opts.SortField || cursor.Dir != tt.opts.SortDir {
+				t.Errorf("cursor = %+v, want id %v sorted by %s %d", cursor, id, tt.opts.SortField, tt.opts.SortDir)
+			}
+			if (cursor.Value == nil) != (tt.want == nil) || (cursor.Value != nil && *cursor.Value != *tt.want) {
+				t.Errorf("cursor value = %v, want %v", cursor.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextTaskCursorRelevance(t *testing.T) {
+	raw, _ := bson.Marshal(bson.M{"_id": primitive.NewObjectID()})
+	opts := taskListOptions{Limit: 20, SortField: sortRelevance, SortDir: -1, After: &taskCursor{Offset: 40}}
+
+	encoded, err := nextTaskCursor(raw, opts)
+	if err != nil {
+		t.Fatalf("nextTaskCursor: %v", err)
+	}
+	cursor, err := decodeTaskCursor(encoded)
+	if err != nil {
+		t.Fatalf("decodeTaskCursor: %v", err)
+	}
+	if cursor.Offset != 60 {
+		t.Errorf("offset = %d, want 60", cursor.Offset)
+	}
+}
+
+func TestDecodeTaskCursorInvalid(t *testing.T) {
+	for _, value := range []string{"", "not base64!", encodeTaskCursor(taskCursor{Sort: "createdAt", Dir: -1})} {
+		if _, err := decodeTaskCursor(value); err == nil {
+			t.Errorf("decodeTaskCursor(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestParseTaskListOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	valid := encodeTaskCursor(taskCursor{Sort: "priority", Dir: -1, ID: primitive.NewObjectID()})
+
+	tests := []struct {
+		query   string
+		wantErr bool
+		sort    string
+		dir     int
+		limit   int
+	}{
+		{"", false, "createdAt", -1, defaultPageSize},
+		{"sort=priority&limit=10", false, "priority", 1, 10},
+		{"sort=-dueDate", false, "dueDate", -1, defaultPageSize},
+		{"q=report", false, sortRelevance, -1, defaultPageSize},
+		{"q=report&sort=relevance", false, sortRelevance, -1, defaultPageSize},
+		{"sort=-priority&cursor=" + valid, false, "priority", -1, defaultPageSize},
+		{"sort=relevance", true, "", 0, 0},
+		{"sort=title", true, "", 0, 0},
+		{"limit=0", true, "", 0, 0},
+		{"limit=201", true, "", 0, 0},
+		{"page=0", true, "", 0, 0},
+		{"page=2&cursor=" + valid, true, "", 0, 0},
+		// The cursor must match the requested order
+		{"sort=priority&cursor=" + valid, true, "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/tasks?"+tt.query, nil)
+
+			opts, err := parseTaskListOptions(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %+v, want an error", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTaskListOptions: %v", err)
+			}
+			if opts.SortField != tt.sort || opts.SortDir != tt.dir || opts.Limit != tt.limit {
+				t.Errorf("got sort %s %d limit %d, want %s %d limit %d", opts.SortField, opts.SortDir, opts.Limit, tt.sort, tt.dir, tt.limit)
+			}
+		})
+	}
+}
+
+func TestPriorityRankOrder(t *testing.T) {
+	ranks := []int{
+		models.TaskPriority("").Rank(),
+		models.PriorityLow.Rank(),
+		models.PriorityMedium.Rank(),
+		models.PriorityHigh.Rank(),
+	}
+	for i := 1; i < len(ranks); i++ {
+		if ranks[i] <= ranks[i-1] {
+			t.Fatalf("ranks of unknown, low, medium, high = %v, want increasing", ranks)
+		}
+	}
+	if ranks[0] != 0 {
+		t.Errorf("unknown priority rank = %d, want 0", ranks[0])
+	}
+}
+
+func ptr(v int64) *int64 {
+	return &v
+}
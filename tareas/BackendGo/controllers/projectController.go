@@ -0,0 +1,453 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-template/middleware"
+	"go-template/models"
+	"go-template/services"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ------------------- functions to interact with MongoDB -------------------
+func getProjectsCollection(c *gin.Context) *mongo.Collection {
+	return services.DB.Collection("projects")
+}
+
+// memberProjectRoles returns the user's role in every project they belong to
+func memberProjectRoles(c *gin.Context, userID primitive.ObjectID) (map[primitive.ObjectID]string, error) {
+	cursor, err := getProjectsCollection(c).Find(context.TODO(),
+		bson.M{"members.userId": userID},
+		options.Find().SetProjection(bson.M{"ownerId": 1, "members": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var projects []models.Project
+	if err := cursor.All(context.TODO(), &projects); err != nil {
+		return nil, err
+	}
+
+	roles := make(map[primitive.ObjectID]string, len(projects))
+	for _, project := range projects {
+		roles[project.ID] = project.RoleOf(userID)
+	}
+	return roles, nil
+}
+
+// updateProject applies an update to the project loaded by RequirePermission
+// and writes the updated project, or a 404 if the filter no longer matches
+func updateProject(c *gin.Context, filter bson.M, update bson.M) {
+	project, _ := middleware.CurrentProject(c)
+
+	var updated models.Project
+	err := getProjectsCollection(c).FindOneAndUpdate(context.TODO(),
+		andFilters(bson.M{"_id": project.ID}, filter),
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project or member not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// ------------------- Project Controller Functions -------------------
+
+// CreateProject creates a project owned by the authenticated user
+func CreateProject(c *gin.Context) {
+	var requestBody struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if strings.TrimSpace(requestBody.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	userObjectID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	// The owner is always the first member
+	now := primitive.NewDateTimeFromTime(time.Now())
+	project := models.Project{
+		ID:          primitive.NewObjectID(),
+		Name:        requestBody.Name,
+		Description: requestBody.Description,
+		OwnerID:     userObjectID,
+		Members:     []models.ProjectMember{{UserID: userObjectID, Role: models.RoleOwner, AddedAt: now}},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	_, err := getProjectsCollection(c).InsertOne(context.TODO(), project)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// GetProjects lists the projects the authenticated user is a member of
+func GetProjects(c *gin.Context) {
+	userObjectID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	cursor, err := getProjectsCollection(c).Find(context.TODO(),
+		bson.M{"members.userId": userObjectID},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var projects []models.Project
+	if err := cursor.All(context.TODO(), &projects); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode projects"})
+		return
+	}
+
+	if projects == nil {
+		projects = []models.Project{}
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// GetProjectByID retrieves a project
+// Requires project:read (see middleware.RequirePermission)
+func GetProjectByID(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+	c.JSON(http.StatusOK, project)
+}
+
+// UpdateProject changes the name or description of a project
+// Requires project:update
+func UpdateProject(c *gin.Context) {
+	var requestBody struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	changes := bson.M{}
+	if requestBody.Name != nil {
+		if strings.TrimSpace(*requestBody.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+			return
+		}
+		changes["name"] = *requestBody.Name
+	}
+	if requestBody.Description != nil {
+		changes["description"] = *requestBody.Description
+	}
+	if len(changes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+	changes["updatedAt"] = primitive.NewDateTimeFromTime(time.Now())
+
+	updateProject(c, nil, bson.M{"$set": changes})
+}
+
+// UpdateProjectWorkflow replaces the statuses and transitions used by the
+// project's tasks. Statuses still in use by a task can't be removed.
+// Requires project:update
+func UpdateProjectWorkflow(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+
+	var workflow models.Workflow
+	if err := c.ShouldBindJSON(&workflow); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := workflow.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Tasks must be moved out of a status before it is removed. The check and
+	// the update run in one transaction so they see the same tasks.
+	var inUse []interface{}
+	var updated models.Project
+	err := services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		var err error
+		inUse, err = getTasksCollection(c).Distinct(sc, "status", bson.M{
+			"projectId": project.ID,
+			"status":    bson.M{"$nin": workflow.Statuses},
+		})
+		if err != nil {
+			return err
+		}
+		if len(inUse) > 0 {
+			return errStatusesInUse
+		}
+
+		return getProjectsCollection(c).FindOneAndUpdate(sc,
+			bson.M{"_id": project.ID},
+			bson.M{"$set": bson.M{
+				"workflow":  workflow,
+				"updatedAt": primitive.NewDateTimeFromTime(time.Now()),
+			}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+	})
+	if err != nil {
+		switch err {
+		case errStatusesInUse:
+			c.JSON(http.StatusConflict, gin.H{"error": "Some tasks still use statuses missing from the workflow", "statuses": inUse})
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// errStatusesInUse aborts a workflow change that removes statuses tasks still use
+var errStatusesInUse = errors.New("statuses in use")
+
+// DeleteProject deletes a project without tasks. Its tasks in the trash are
+// purged along with it.
+// Requires project:delete
+func DeleteProject(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+
+	// Tasks must be moved or deleted first so none are left without a board.
+	// The check and the delete run in one transaction so they see the same tasks.
+	err := services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		count, err := getTasksCollection(c).CountDocuments(sc, andFilters(bson.M{"projectId": project.ID}, notDeleted()))
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errProjectHasTasks
+		}
+
+		result, err := getProjectsCollection(c).DeleteOne(sc, bson.M{"_id": project.ID})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	})
+	if err != nil {
+		switch err {
+		case errProjectHasTasks:
+			c.JSON(http.StatusConflict, gin.H{"error": "Project still has tasks"})
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		}
+		return
+	}
+
+	// Deleted tasks can't be restored without their project
+	err = services.PurgeTasks(context.TODO(), bson.M{"projectId": project.ID, "deletedAt": bson.M{"$exists": true}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge the project's deleted tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// errProjectHasTasks aborts deleting a project that still has live tasks
+var errProjectHasTasks = errors.New("project has tasks")
+
+// AddProjectMember adds an existing user to a project with a role (member by default)
+// Requires project:members; only the owner can add admins
+func AddProjectMember(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+
+	var requestBody struct {
+		UserID primitive.ObjectID `json:"userId"`
+		Role   string             `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil || requestBody.UserID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "UserId is required"})
+		return
+	}
+
+	if requestBody.Role == "" {
+		requestBody.Role = models.RoleMember
+	}
+	if !models.IsAssignableRole(requestBody.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Valid values: admin, member, viewer"})
+		return
+	}
+	if requestBody.Role == models.RoleAdmin && !isProjectOwner(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can manage admins"})
+		return
+	}
+
+	if project.RoleOf(requestBody.UserID) != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+		return
+	}
+
+	// The new member must be a registered user
+	err := getUserCollection(c).FindOne(context.TODO(), bson.M{"_id": requestBody.UserID}).Err()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+
+	member := models.ProjectMember{
+		UserID:  requestBody.UserID,
+		Role:    requestBody.Role,
+		AddedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	updateProject(c,
+		bson.M{"members.userId": bson.M{"$ne": member.UserID}},
+		bson.M{
+			"$push": bson.M{"members": member},
+			"$set":  bson.M{"updatedAt": member.AddedAt},
+		},
+	)
+}
+
+// UpdateProjectMemberRole changes the role of a project member
+// Requires project:members; only the owner can promote to or demote from admin
+func UpdateProjectMemberRole(c *gin.Context) {
+	memberID, ok := checkMemberChange(c)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role is required"})
+		return
+	}
+
+	if !models.IsAssignableRole(requestBody.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Valid values: admin, member, viewer"})
+		return
+	}
+	if requestBody.Role == models.RoleAdmin && !isProjectOwner(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can manage admins"})
+		return
+	}
+
+	updateProject(c,
+		bson.M{"members.userId": memberID},
+		bson.M{"$set": bson.M{
+			"members.$.role": requestBody.Role,
+			"updatedAt":      primitive.NewDateTimeFromTime(time.Now()),
+		}},
+	)
+}
+
+// RemoveProjectMember removes a user from a project and releases their
+// tasks in it (see unassignUser)
+// Requires project:members; the owner can't be removed
+func RemoveProjectMember(c *gin.Context) {
+	memberID, ok := checkMemberChange(c)
+	if !ok {
+		return
+	}
+
+	// Former members can't keep working on the project's tasks
+	project, _ := middleware.CurrentProject(c)
+	if err := unassignUser(c, memberID, bson.M{"projectId": project.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release the member's tasks"})
+		return
+	}
+
+	updateProject(c,
+		bson.M{"members.userId": memberID},
+		bson.M{
+			"$pull": bson.M{"members": bson.M{"userId": memberID}},
+			"$set":  bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+		},
+	)
+}
+
+// checkMemberChange validates the :userId of a member being changed or
+// removed. Nobody can change the owner, and only the owner can change admins.
+func checkMemberChange(c *gin.Context) (primitive.ObjectID, bool) {
+	project, _ := middleware.CurrentProject(c)
+
+	memberID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return memberID, false
+	}
+
+	switch project.RoleOf(memberID) {
+	case "":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return memberID, false
+	case models.RoleOwner:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The owner's membership can't be changed"})
+		return memberID, false
+	case models.RoleAdmin:
+		if !isProjectOwner(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can manage admins"})
+			return memberID, false
+		}
+	}
+
+	return memberID, true
+}
+
+// isProjectOwner reports whether the authenticated user owns the current project
+func isProjectOwner(c *gin.Context) bool {
+	project, _ := middleware.CurrentProject(c)
+	userObjectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	return err == nil && project.OwnerID == userObjectID
+}
+
+// GetProjectTasks lists the tasks of a project with the same filters, sorting
+// and paging as GET /tasks
+// Requires project:read
+func GetProjectTasks(c *gin.Context) {
+	project, _ := middleware.CurrentProject(c)
+	workflow := project.TaskWorkflow()
+	listTasks(c, bson.M{"projectId": project.ID}, &workflow)
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"go-template/middleware"
+	"go-template/models"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ------------------- Reactions -------------------
+// Users react to comments with emojis. Each user reacts at most once with
+// each emoji; reacting again with the same one takes the reaction back.
+
+// maxEmojiLength bounds an emoji in runes. Emojis with skin tones or joined
+// with zero-width joiners take several runes.
+const maxEmojiLength = 16
+
+// ToggleCommentReaction adds the authenticated user's reaction with the
+// emoji in the body to the comment, or removes it if it was already there,
+// and returns the comment's reaction counts
+// Requires comment:create on the comment's task
+func ToggleCommentReaction(c *gin.Context) {
+	comment := middleware.CurrentComment(c)
+
+	var requestBody struct {
+		Emoji string `json:"emoji"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	emoji := requestBody.Emoji
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxEmojiLength || strings.ContainsAny(emoji, " \t\r\n") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid emoji"})
+		return
+	}
+
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	reaction := models.CommentReaction{Emoji: emoji, UserID: userID}
+	collection := getCommentsCollection(c)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	// Take the reaction back if the user already reacted with the emoji,
+	// otherwise add it
+	var updated models.Comment
+	err := collection.FindOneAndUpdate(context.TODO(),
+		andFilters(bson.M{"_id": comment.ID, "reactions": bson.M{"$elemMatch": bson.M{"emoji": emoji, "userId": userID}}}, notDeleted()),
+		bson.M{"$pull": bson.M{"reactions": bson.M{"emoji": emoji, "userId": userID}}},
+		opts,
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		err = collection.FindOneAndUpdate(context.TODO(),
+			andFilters(bson.M{"_id": comment.ID}, notDeleted()),
+			bson.M{"$addToSet": bson.M{"reactions": reaction}},
+			opts,
+		).Decode(&updated)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reactions": updated.View(userID).Reactions})
+}
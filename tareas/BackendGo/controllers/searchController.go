@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"go-template/models"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// snippetRadius is how many bytes of context are kept around the first match
+const snippetRadius = 60
+
+// SearchAll runs a full-text search over the tasks the user can see and the
+// comments on those tasks, returning both lists ranked by relevance
+func SearchAll(c *gin.Context) {
+	access, ok := getTaskAccess(c)
+	if !ok {
+		return
+	}
+
+	q, err := parseSearchQuery(c.Query("q"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	visible := access.visible()
+
+	// Tasks reuse the GET /tasks pipeline ordered by relevance
+	opts := taskListOptions{Limit: limit, SortField: sortRelevance, SortDir: -1, Search: q}
+	filter := andFilters(visible, notDeleted())
+	filter["$text"] = bson.M{"$search": q}
+
+	cursor, err := getTasksCollection(c).Aggregate(context.TODO(), taskListPipeline(filter, opts))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search tasks"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var docs []bson.Raw
+	if err := cursor.All(context.TODO(), &docs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode tasks"})
+		return
+	}
+
+	tasks, _, err := decodeTaskPage(docs, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode tasks"})
+		return
+	}
+
+	comments, err := searchComments(c, q, visible, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":    q,
+		"tasks":    tasks,
+		"comments": comments,
+	})
+}
+
+// searchComments finds comments matching q whose task passes the visibility filter
+func searchComments(c *gin.Context, q string, visible bson.M, limit int) ([]models.CommentResult, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: andFilters(bson.M{"$text": bson.M{"$search": q}}, notDeleted())}},
+		{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}}}},
+		// Keep only comments on tasks the user is allowed to read
+		{{Key: "$lookup", Value: bson.M{
+			"from": "tasks",
+			"let":  bson.M{"taskId": "$taskId"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$taskId"}}}},
+				bson.M{"$match": andFilters(visible, notDeleted())},
+				bson.M{"$project": bson.M{"title": 1}},
+			},
+			"as": "task",
+		}}},
+		{{Key: "$match", Value: bson.M{"task": bson.M{"$ne": bson.A{}}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$addFields", Value: bson.M{"taskTitle": bson.M{"$first": "$task.title"}}}},
+		{{Key: "$project", Value: bson.M{"task": 0}}},
+	}
+
+	cursor, err := getCommentsCollection(c).Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	comments := []models.CommentResult{}
+	if err := cursor.All(context.TODO(), &comments); err != nil {
+		return nil, err
+	}
+
+	terms := searchTermsPattern(q)
+	for i := range comments {
+		comments[i].Highlight = highlightText(comments[i].Text, terms)
+	}
+
+	return comments, nil
+}
+
+// highlightTask returns highlighted snippets for the task fields matching q
+func highlightTask(task models.Task, q string) map[string]string {
+	terms := searchTermsPattern(q)
+	highlights := map[string]string{}
+	if snippet := highlightText(task.Title, terms); snippet != "" {
+		highlights["title"] = snippet
+	}
+	if snippet := highlightText(task.Description, terms); snippet != "" {
+		highlights["description"] = snippet
+	}
+	if len(highlights) == 0 {
+		return nil
+	}
+	return highlights
+}
+
+// searchTermsPattern builds a case-insensitive regexp matching the words of a
+// text search. Negated terms ("-word") are skipped and each term also matches
+// longer words starting with it, roughly following the index stemming.
+func searchTermsPattern(q string) *regexp.Regexp {
+	var terms []string
+	for _, term := range strings.Fields(strings.ReplaceAll(q, `"`, " ")) {
+		if strings.HasPrefix(term, "-") || utf8.RuneCountInString(term) < 2 {
+			continue
+		}
+		terms = append(terms, regexp.QuoteMeta(term)+`\w*`)
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(terms, "|"))
+}
+
+// highlightText returns a snippet of text around the first match with every
+// match wrapped in <mark>. The rest of the text is HTML escaped. It returns
+// an empty string when nothing matches.
+func highlightText(text string, terms *regexp.Regexp) string {
+	if terms == nil {
+		return ""
+	}
+	matches := terms.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+
+	// Cut a window around the first match on rune boundaries
+	start := max(matches[0][0]-snippetRadius, 0)
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	end := min(matches[0][1]+snippetRadius, len(text))
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m[0] < start || m[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		pos = m[1]
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"go-template/models"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSearchTermsPattern(t *testing.T) {
+	tests := []struct {
+		q    string
+		want string // empty when no pattern is built
+	}{
+		{"informe", `(?i)informe\w*`},
+		{"informe -borrador", `(?i)informe\w*`},
+		{`"informe anual"`, `(?i)informe\w*|anual\w*`},
+		{"a.b", `(?i)a\.b\w*`},
+		{"a", ""},
+		{"-borrador", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			pattern := searchTermsPattern(tt.q)
+			got := ""
+			if pattern != nil {
+				got = pattern.String()
+			}
+			if got != tt.want {
+				t.Errorf("searchTermsPattern(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightText(t *testing.T) {
+	long := strings.Repeat("x", 100)
+
+	tests := []struct {
+		name string
+		text string
+		q    string
+		want string
+	}{
+		{"no match", "Revisar el informe", "presupuesto", ""},
+		{"no terms", "Revisar el informe", "-informe", ""},
+		{"case and prefix", "Revisar INFORMES", "informe", "Revisar <mark>INFORMES</mark>"},
+		{"every match", "uno dos uno", "uno", "<mark>uno</mark> dos <mark>uno</mark>"},
+		{"escapes html", `<b>informe</b> & "más"`, "informe", "&lt;b&gt;<mark>informe</mark>&lt;/b&gt; &amp; &#34;más&#34;"},
+		{"escapes match", "a<b", "a<b", "<mark>a&lt;b</mark>"},
+		{"window on both sides", long + " informe " + long, "informe",
+			"…" + strings.Repeat("x", snippetRadius-1) + " <mark>informe</mark> " + strings.Repeat("x", snippetRadius-1) + "…"},
+		{"match outside the window", "informe " + long + " informe", "informe",
+			"<mark>informe</mark> " + strings.Repeat("x", snippetRadius-1) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightText(tt.text, searchTermsPattern(tt.q)); got != tt.want {
+				t.Errorf("highlightText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightTextRuneBoundaries(t *testing.T) {
+	// Multi-byte runes around the match must not be cut in half
+	for _, pad := range []string{"é", "ñá", "日本", "🙂"} {
+		for shift := 0; shift < 4; shift++ {
+			text := strings.Repeat("a", shift) + strings.Repeat(pad, 40) + " informe " + strings.Repeat(pad, 40)
+			got := highlightText(text, searchTermsPattern("informe"))
+			if !utf8.ValidString(got) {
+				t.Errorf("pad %q shift %d: snippet %q is not valid UTF-8", pad, shift, got)
+			}
+			if !strings.Contains(got, "<mark>informe</mark>") {
+				t.Errorf("pad %q shift %d: snippet %q misses the match", pad, shift, got)
+			}
+		}
+	}
+}
+
+func TestHighlightTask(t *testing.T) {
+	task := models.Task{Title: "Informe anual", Description: "Sin coincidencias"}
+	want := map[string]string{"title": "<mark>Informe</mark> anual"}
+	if got := highlightTask(task, "informe"); !reflect.DeepEqual(got, want) {
+		t.Errorf("highlightTask = %v, want %v", got, want)
+	}
+	if got := highlightTask(task, "presupuesto"); got != nil {
+		t.Errorf("highlightTask without matches = %v, want nil", got)
+	}
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"go-template/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ------------------- Task access policy -------------------
+// Handlers working on a single task or comment are authorized by
+// middleware.RequirePermission. The helpers here build the filters for the
+// endpoints that list many tasks, following the same rules as
+// Task.RolesOf: a user sees their personal tasks, the tasks they are
+// assigned to and every task of their projects.
+
+// getAuthUserID returns the authenticated user's ID set by AuthMiddleware.
+// It writes a 400 response and returns false if the ID is malformed.
+func getAuthUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, _ := c.MustGet("userID").(string)
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return primitive.NilObjectID, false
+	}
+	return userObjectID, true
+}
+
+// Values accepted by the scope query parameter of GET /tasks
+const (
+	TaskScopeCreated  = "created"
+	TaskScopeAssigned = "assigned"
+	TaskScopeWatching = "watching"
+	TaskScopeAll      = "all"
+)
+
+// taskAccess describes what the authenticated user can reach. It is resolved
+// once per request by getTaskAccess.
+type taskAccess struct {
+	UserID       primitive.ObjectID
+	ProjectRoles map[primitive.ObjectID]string // role in each project the user is a member of
+}
+
+// getTaskAccess resolves the authenticated user and their project
+// memberships. It writes an error response and returns false on failure.
+func getTaskAccess(c *gin.Context) (taskAccess, bool) {
+	userObjectID, ok := getAuthUserID(c)
+	if !ok {
+		return taskAccess{}, false
+	}
+
+	projectRoles, err := memberProjectRoles(c, userObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project memberships"})
+		return taskAccess{}, false
+	}
+
+	return taskAccess{UserID: userObjectID, ProjectRoles: projectRoles}, true
+}
+
+// visible matches the tasks the user can read, like Task.RolesOf: their
+// personal tasks, the ones they are assigned to, and every task of their
+// projects
+func (a taskAccess) visible() bson.M {
+	conditions := bson.A{
+		a.personal(),
+		bson.M{"assignees": a.UserID},
+	}
+	if projects := a.memberProjects(); projects != nil {
+		conditions = append(conditions, projects)
+	}
+	return bson.M{"$or": conditions}
+}
+
+// owned matches the tasks the user created and can still read: personal
+// tasks, and project tasks while they are a member of the project
+func (a taskAccess) owned() bson.M {
+	projects := a.memberProjects()
+	if projects == nil {
+		return a.personal()
+	}
+	return andFilters(bson.M{"createdBy": a.UserID}, bson.M{"$or": bson.A{bson.M{"projectId": bson.M{"$exists": false}}, projects}})
+}
+
+// personal matches the personal tasks the user created
+func (a taskAccess) personal() bson.M {
+	return bson.M{"createdBy": a.UserID, "projectId": bson.M{"$exists": false}}
+}
+
+// memberProjects matches the tasks of the user's projects, or returns nil if
+// they aren't a member of any
+func (a taskAccess) memberProjects() bson.M {
+	if len(a.ProjectRoles) == 0 {
+		return nil
+	}
+	projectIDs := make(bson.A, 0, len(a.ProjectRoles))
+	for id := range a.ProjectRoles {
+		projectIDs = append(projectIDs, id)
+	}
+	return bson.M{"projectId": bson.M{"$in": projectIDs}}
+}
+
+// scope returns the access filter for a GET /tasks scope value
+func (a taskAccess) scope(scope string) (bson.M, bool) {
+	switch scope {
+	case "", TaskScopeCreated:
+		return a.owned(), true
+	case TaskScopeAssigned:
+		return bson.M{"assignees": a.UserID}, true
+	case TaskScopeWatching:
+		return andFilters(bson.M{"watchers": a.UserID}, a.visible()), true
+	case TaskScopeAll:
+		return a.visible(), true
+	}
+	return nil, false
+}
+
+// checkProjectPermission verifies the user holds a permission on a project.
+// Projects the user isn't a member of are reported as 404. It writes the
+// error response and returns false on failure.
+func (a taskAccess) checkProjectPermission(c *gin.Context, projectID primitive.ObjectID, permission string) bool {
+	role, ok := a.ProjectRoles[projectID]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return false
+	}
+	if !models.RoleAllows(role, permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do this (" + permission + ")"})
+		return false
+	}
+	return true
+}
+
+// andFilters combines filters so that a document must match all of them
+func andFilters(filters ...bson.M) bson.M {
+	conditions := bson.A{}
+	for _, f := range filters {
+		if len(f) > 0 {
+			conditions = append(conditions, f)
+		}
+	}
+	if len(conditions) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": conditions}
+}
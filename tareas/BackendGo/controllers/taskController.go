@@ -2,20 +2,170 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"go-template/middleware"
 	"go-template/models"
 	"go-template/services"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // ------------------- functions to interact with MongoDB -------------------
 func getTasksCollection(c *gin.Context) *mongo.Collection {
-	return services.Client.Database("task_db").Collection("tasks")
+	return services.DB.Collection("tasks")
+}
+
+// ------------------- Validation -------------------
+
+// validateTask checks the editable fields of a task against the workflow and
+// label catalog of its project (the zero project for personal tasks). It is
+// shared by CreateTask and UpdateTask so both endpoints accept the same values.
+func validateTask(task *models.Task, project models.Project) error {
+	if strings.TrimSpace(task.Title) == "" {
+		return errors.New("Title is required")
+	}
+
+	task.NormalizeAssignees()
+	if len(task.Assignees) == 0 {
+		return errors.New("At least one assignee is required")
+	}
+
+	if err := project.TaskWorkflow().ValidateTaskState(task.Status, task.Priority); err != nil {
+		return err
+	}
+	task.PriorityRank = task.Priority.Rank()
+
+	// Personal tasks can use any label, project tasks only the project's
+	labels, err := models.NormalizeLabels(task.Labels)
+	if err != nil {
+		return err
+	}
+	for _, label := range labels {
+		if !project.ID.IsZero() && !project.HasLabel(label) {
+			return errors.New("Label " + label + " is not defined in the project")
+		}
+	}
+	task.Labels = labels
+
+	if task.StartDate != nil && task.DueDate != nil && task.DueDate.Time().Before(task.StartDate.Time()) {
+		return errors.New("DueDate must not be before startDate")
+	}
+
+	return nil
+}
+
+// loadTaskProject returns the project of a task, or the zero project for
+// personal tasks. It writes the error response and returns false on failure.
+func loadTaskProject(c *gin.Context, task models.Task) (models.Project, bool) {
+	if task.ProjectID == nil {
+		return models.Project{}, true
+	}
+	projectID := *task.ProjectID
+	if project, ok := middleware.CurrentProject(c); ok && project.ID == projectID {
+		return project, true
+	}
+
+	var project models.Project
+	err := getProjectsCollection(c).FindOne(context.TODO(), bson.M{"_id": projectID}).Decode(&project)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return project, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve project"})
+		return project, false
+	}
+	return project, true
+}
+
+// checkAssignees verifies that every assignee is an existing user and, for
+// project tasks, a member of the project. It writes a 422 listing the
+// rejected IDs and returns false otherwise.
+func checkAssignees(c *gin.Context, task models.Task, project models.Project) bool {
+	var invalid []primitive.ObjectID
+
+	cursor, err := getUserCollection(c).Find(context.TODO(),
+		bson.M{"_id": bson.M{"$in": task.Assignees}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check assignees"})
+		return false
+	}
+	var users []models.User
+	if err := cursor.All(context.TODO(), &users); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check assignees"})
+		return false
+	}
+
+	existing := map[primitive.ObjectID]bool{}
+	for _, user := range users {
+		existing[user.ID] = true
+	}
+	for _, id := range task.Assignees {
+		if !existing[id] {
+			invalid = append(invalid, id)
+		}
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Assignees must be existing users", "invalidAssignees": invalid})
+		return false
+	}
+
+	if !project.ID.IsZero() {
+		for _, id := range task.Assignees {
+			if project.RoleOf(id) == "" {
+				invalid = append(invalid, id)
+			}
+		}
+		if len(invalid) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Assignees must be members of the project", "invalidAssignees": invalid})
+			return false
+		}
+	}
+
+	return true
+}
+
+// optionalDate is a date field of a PATCH body. It tells an absent field
+// (leave unchanged) apart from an explicit null (clear the date).
+type optionalDate struct {
+	Set   bool
+	Value *primitive.DateTime
+}
+
+func (d *optionalDate) UnmarshalJSON(data []byte) error {
+	d.Set = true
+	if string(data) == "null" {
+		d.Value = nil
+		return nil
+	}
+	var value primitive.DateTime
+	if err := value.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	d.Value = &value
+	return nil
+}
+
+// parseDateParam parses a query date given as RFC 3339 or YYYY-MM-DD
+func parseDateParam(value string) (primitive.DateTime, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return primitive.NewDateTimeFromTime(t), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return 0, err
+	}
+	return primitive.NewDateTimeFromTime(t), nil
 }
 
 // ------------------- Task Controller Functions -------------------
@@ -29,93 +179,174 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
-	if task.Title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+	// Get the authenticated user and their projects
+	access, ok := getTaskAccess(c)
+	if !ok {
 		return
 	}
 
-	// Validate required fields
-	if task.AssignedTo.IsZero() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "AssignedTo is required"})
+	// Automatically assign createdBy to the authenticated user
+	task.CreatedBy = access.UserID
+
+	// Tasks can only be added to projects where the user has task:create
+	if task.ProjectID != nil && task.ProjectID.IsZero() {
+		task.ProjectID = nil
+	}
+	if task.ProjectID != nil && !access.checkProjectPermission(c, *task.ProjectID, models.PermTaskCreate) {
 		return
 	}
 
-	// Get the authenticated user ID from middleware
-	userID := c.MustGet("userID").(string)
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	// Subtasks are checked against their parent, see CreateSubtask
+	if task.ParentID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use POST /tasks/:id/subtasks to create subtasks"})
 		return
 	}
 
-	// Automatically assign createdBy to the authenticated user
-	task.CreatedBy = userObjectID
+	insertTask(c, task)
+}
+
+// CreateSubtask creates a task under the task in :id. Subtasks belong to
+// the project of their parent.
+// Requires task:create on the parent task
+func CreateSubtask(c *gin.Context) {
+	parent := middleware.CurrentTask(c)
+
+	var task models.Task
+	if err := c.ShouldBindJSON(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
 
+	task.CreatedBy = userID
+	task.ParentID = &parent.ID
+	task.ProjectID = parent.ProjectID
+
+	insertTask(c, task)
+}
+
+// insertTask fills in the defaults of a new task, validates it against the
+// workflow of its project and stores it. Shared by CreateTask and CreateSubtask.
+func insertTask(c *gin.Context, task models.Task) {
 	collection := getTasksCollection(c)
 	if collection == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to tasks collection"})
 		return
 	}
 
+	project, ok := loadTaskProject(c, task)
+	if !ok {
+		return
+	}
+
 	// Set default values if not provided
 	if task.Status == "" {
-		task.Status = "pendiente"
+		task.Status = project.TaskWorkflow().Initial()
 	}
 	if task.Priority == "" {
-		task.Priority = "media"
+		task.Priority = models.PriorityMedium
 	}
 
-	// Validate status and priority
-	if task.Status != "pendiente" && task.Status != "en_progreso" && task.Status != "completada" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid values: pendiente, en_progreso, completada"})
+	// Validate required fields, status and priority
+	if err := validateTask(&task, project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if task.Priority != "baja" && task.Priority != "media" && task.Priority != "alta" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority. Valid values: baja, media, alta"})
+	if !checkAssignees(c, task, project) {
 		return
 	}
 
 	// Generate values for the task
 	task.ID = primitive.NewObjectID()
+	task.SoftDelete = models.SoftDelete{}
+	task.Archived, task.ArchivedAt, task.CompletedAt = false, nil, nil
+	// Dependencies and watchers are only added through their own endpoints,
+	// which check the linked tasks and users
+	task.BlockedBy = nil
+	task.Watchers = nil
 	now := primitive.NewDateTimeFromTime(time.Now())
 	task.CreatedAt = now
 	task.UpdatedAt = now
+	task.Version = 1
+	if task.Status == models.StatusCompleted {
+		task.CompletedAt = &now
+	}
+
+	// Checklist items sent on creation get their own IDs
+	for i := range task.Checklist {
+		if strings.TrimSpace(task.Checklist[i].Text) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Checklist item text is required"})
+			return
+		}
+		task.Checklist[i].ID = primitive.NewObjectID()
+		task.Checklist[i].CreatedAt = now
+	}
 
-	_, err = collection.InsertOne(context.TODO(), task)
+	_, err := collection.InsertOne(context.TODO(), task)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, task)
+	recordTaskChange(c, models.ActivityTaskCreated, models.Task{}, task)
+
+	setTaskETag(c, task)
+	c.JSON(http.StatusCreated, task.Result(models.SubtaskCount{}))
 }
 
 // GetTasks retrieves all tasks with optional filtering
 func GetTasks(c *gin.Context) {
+	access, ok := getTaskAccess(c)
+	if !ok {
+		return
+	}
+
+	// Restrict results to the requested scope (created by default)
+	scopeFilter, ok := access.scope(c.Query("scope"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope. Valid values: created, assigned, watching, all"})
+		return
+	}
+
+	listTasks(c, scopeFilter, nil)
+}
+
+// listTasks writes one page of the tasks matching the base filter, narrowed
+// by the query string filters. Used by GET /tasks and GET /projects/:id/tasks.
+// When workflow is set the status filter is validated against it.
+func listTasks(c *gin.Context, scopeFilter bson.M, workflow *models.Workflow) {
 	collection := getTasksCollection(c)
 	if collection == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to tasks collection"})
 		return
 	}
 
-	userID := c.MustGet("userID").(string)
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	// Read limit, page/cursor and sort parameters
+	opts, err := parseTaskListOptions(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Build filter based on query parameters
-	filter := bson.M{
-		"createdBy": userObjectID, 
-	}
+	filter := bson.M{}
 
 	// Filter by status if provided
-	if status := c.Query("status"); status != "" {
-		// Validate status values according to schema
-		if status != "pendiente" && status != "en_progreso" && status != "completada" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid values: pendiente, en_progreso, completada"})
+	if status := models.TaskStatus(c.Query("status")); status != "" {
+		// Validate status values against the project workflow when listing a
+		// single project; lists spanning projects only check the format
+		if workflow != nil {
+			if err := workflow.ValidateStatus(status); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		} else if !status.WellFormed() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
 			return
 		}
 		filter["status"] = status
@@ -123,13 +354,32 @@ func GetTasks(c *gin.Context) {
 
 	// Filter by priority if provided
 	if priority := c.Query("priority"); priority != "" {
-		if priority != "baja" && priority != "media" && priority != "alta" {
+		if !models.TaskPriority(priority).Valid() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority. Valid values: baja, media, alta"})
 			return
 		}
 		filter["priority"] = priority
 	}
 
+	// Filter by labels, matching tasks with any of them or, with
+	// labelMatch=all, only tasks that have every label
+	if labels := c.QueryArray("label"); len(labels) > 0 {
+		labels, err := models.NormalizeLabels(labels)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		switch c.DefaultQuery("labelMatch", "any") {
+		case "any":
+			filter["labels"] = bson.M{"$in": labels}
+		case "all":
+			filter["labels"] = bson.M{"$all": labels}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid labelMatch. Valid values: any, all"})
+			return
+		}
+	}
+
 	// Filter by assignedTo if provided
 	if assignedTo := c.Query("assignedTo"); assignedTo != "" {
 		assignedToID, err := primitive.ObjectIDFromHex(assignedTo)
@@ -137,116 +387,468 @@ func GetTasks(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignedTo ID"})
 			return
 		}
-		filter["assignedTo"] = assignedToID
+		filter["assignees"] = assignedToID
+	}
+
+	// Filter by projectId if provided
+	if projectID := c.Query("projectId"); projectID != "" {
+		projectObjectID, err := primitive.ObjectIDFromHex(projectID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid projectId"})
+			return
+		}
+		filter["projectId"] = projectObjectID
+	}
+
+	// Filter by due date range if provided
+	dueRange := bson.M{}
+	if dueBefore := c.Query("dueBefore"); dueBefore != "" {
+		date, err := parseDateParam(dueBefore)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dueBefore. Use YYYY-MM-DD or RFC 3339"})
+			return
+		}
+		dueRange["$lt"] = date
+	}
+	if dueAfter := c.Query("dueAfter"); dueAfter != "" {
+		date, err := parseDateParam(dueAfter)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dueAfter. Use YYYY-MM-DD or RFC 3339"})
+			return
+		}
+		dueRange["$gt"] = date
+	}
+	if len(dueRange) > 0 {
+		filter["dueDate"] = dueRange
+	}
+
+	// Filter by overdue state if provided
+	overdueFilter := bson.M{}
+	if overdue := c.Query("overdue"); overdue != "" {
+		isOverdue, err := strconv.ParseBool(overdue)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid overdue. Valid values: true, false"})
+			return
+		}
+		now := primitive.NewDateTimeFromTime(time.Now())
+		if isOverdue {
+			overdueFilter = bson.M{"dueDate": bson.M{"$lt": now}, "status": bson.M{"$ne": models.StatusCompleted}}
+		} else {
+			overdueFilter = bson.M{"$or": bson.A{
+				bson.M{"dueDate": nil},
+				bson.M{"dueDate": bson.M{"$gte": now}},
+				bson.M{"status": models.StatusCompleted},
+			}}
+		}
+	}
+
+	// Archived tasks are left out unless asked for
+	var archivedFilter bson.M
+	includeArchived, err := strconv.ParseBool(c.DefaultQuery("includeArchived", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid includeArchived. Use true or false"})
+		return
+	}
+	if !includeArchived {
+		archivedFilter = bson.M{"archived": bson.M{"$ne": true}}
+	}
+
+	filter = andFilters(scopeFilter, filter, overdueFilter, archivedFilter, notDeleted())
+
+	// Full-text search on title and description
+	if opts.Search != "" {
+		filter["$text"] = bson.M{"$search": opts.Search}
+	}
+
+	// Total number of matching tasks across all pages. Counting scans every
+	// match, so it is only done for the first page and numbered pages;
+	// clients following cursors keep the total they got first.
+	total := int64(-1)
+	if opts.After == nil {
+		total, err = collection.CountDocuments(context.TODO(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+			return
+		}
 	}
 
-	// Find tasks with filter
-	cursor, err := collection.Find(context.TODO(), filter)
+	// Find one page of tasks with filter
+	cursor, err := collection.Aggregate(context.TODO(), taskListPipeline(filter, opts))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
 		return
 	}
 	defer cursor.Close(context.TODO())
 
-	var tasks []models.Task
-	if err := cursor.All(context.TODO(), &tasks); err != nil {
+	var docs []bson.Raw
+	if err := cursor.All(context.TODO(), &docs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode tasks"})
+		return
+	}
+
+	tasks, nextCursor, err := decodeTaskPage(docs, opts)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode tasks"})
 		return
 	}
 
-	if tasks == nil {
-		tasks = []models.Task{}
+	// Paging metadata goes in headers so the body stays a plain array
+	if total >= 0 {
+		c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+	if nextCursor != "" {
+		c.Header("X-Next-Cursor", nextCursor)
 	}
 
 	c.JSON(http.StatusOK, tasks)
 }
 
 // GetTaskByID retrieves a single task by its ID
+// Requires task:read on the task (see middleware.RequirePermission)
 func GetTaskByID(c *gin.Context) {
-	var task models.Task
-	taskID, err := primitive.ObjectIDFromHex(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+	task := middleware.CurrentTask(c)
+	setTaskETag(c, task)
+	c.JSON(http.StatusOK, taskResult(task))
+}
+
+// GetSubtasks lists the subtasks of the task in :id that the user can see,
+// with the same filters, sorting and paging as GET /tasks
+// Requires task:read on the parent task
+func GetSubtasks(c *gin.Context) {
+	parent := middleware.CurrentTask(c)
+
+	access, ok := getTaskAccess(c)
+	if !ok {
 		return
 	}
-	collection := getTasksCollection(c)
-	if collection == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to tasks collection"})
+
+	var workflow *models.Workflow
+	if project, ok := middleware.CurrentProject(c); ok {
+		w := project.TaskWorkflow()
+		workflow = &w
+	}
+
+	listTasks(c, andFilters(bson.M{"parentId": parent.ID}, access.visible()), workflow)
+}
+
+// subtaskCountStages groups the subtasks matched by the previous stages into
+// a single models.SubtaskCount document, leaving out deleted ones
+func subtaskCountStages() bson.A {
+	return bson.A{
+		bson.M{"$match": notDeleted()},
+		bson.M{"$group": bson.M{
+			"_id":   nil,
+			"total": bson.M{"$sum": 1},
+			"done":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", models.StatusCompleted}}, 1, 0}}},
+		}},
+	}
+}
+
+// countSubtasks counts the subtasks of a task and how many are completed
+func countSubtasks(taskID primitive.ObjectID) (models.SubtaskCount, error) {
+	var count models.SubtaskCount
+
+	pipeline := append(bson.A{bson.M{"$match": bson.M{"parentId": taskID}}}, subtaskCountStages()...)
+	cursor, err := services.DB.Collection("tasks").Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return count, err
+	}
+	defer cursor.Close(context.TODO())
+
+	if cursor.Next(context.TODO()) {
+		err = cursor.Decode(&count)
+	}
+	if err == nil {
+		err = cursor.Err()
+	}
+	return count, err
+}
+
+// taskResult returns a single task as sent in responses. If the subtasks
+// can't be counted the progress only covers the checklist, so a write that
+// already succeeded isn't reported as failed.
+func taskResult(task models.Task) models.TaskResult {
+	subtasks, _ := countSubtasks(task.ID)
+	return task.Result(subtasks)
+}
+
+// DeleteTask moves a task to the trash together with its subtasks and
+// comments, and reports how many of each went along with it
+// Requires task:delete on the task
+func DeleteTask(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	userID, ok := getAuthUserID(c)
+	if !ok {
 		return
 	}
-	err = collection.FindOne(context.TODO(), bson.M{"_id": taskID}).Decode(&task)
+
+	var removed taskRemoval
+	err := services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		var err error
+		removed, err = trashTaskTree(c, sc, task.ID, userID)
+		return err
+	})
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve task"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
 		return
 	}
-	c.JSON(http.StatusOK, task)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task moved to the trash", "removed": removed})
 }
 
-// DeleteTask deletes a task by ID
-func DeleteTask(c *gin.Context) {
-	taskID, err := primitive.ObjectIDFromHex(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+// UpdateTask applies a partial update to a task. Any subset of the editable
+// fields can be sent; the merged result is validated like CreateTask.
+// Requires task:update on the task
+func UpdateTask(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+	previous := task
+
+	// Nil fields are left untouched
+	var requestBody struct {
+		Title       *string               `json:"title"`
+		Description *string               `json:"description"`
+		AssignedTo  *primitive.ObjectID   `json:"assignedTo"` // replaces the assignees with one user
+		Assignees   *[]primitive.ObjectID `json:"assignees"`
+		Status      *models.TaskStatus    `json:"status"`
+		Priority    *models.TaskPriority  `json:"priority"`
+		StartDate   optionalDate          `json:"startDate"`
+		DueDate     optionalDate          `json:"dueDate"`
+		ProjectID   *primitive.ObjectID   `json:"projectId"`
+		Labels      *[]string             `json:"labels"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	// Get the authenticated user ID
-	userID := c.MustGet("userID").(string)
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	// Reject the edit if the client saw an older version
+	if !checkIfMatch(c, task) {
 		return
 	}
 
-	collection := getTasksCollection(c)
-	if collection == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to tasks collection"})
+	changes := bson.M{}
+	unset := bson.M{}
+	if requestBody.Title != nil {
+		task.Title = *requestBody.Title
+		changes["title"] = task.Title
+	}
+	if requestBody.Description != nil {
+		task.Description = *requestBody.Description
+		changes["description"] = task.Description
+	}
+	if requestBody.AssignedTo != nil {
+		task.AssignedTo = *requestBody.AssignedTo
+		task.Assignees = nil
+		changes["assignees"] = nil
+	}
+	if requestBody.Assignees != nil {
+		task.Assignees = *requestBody.Assignees
+		changes["assignees"] = nil
+	}
+	if requestBody.Status != nil {
+		if *requestBody.Status != task.Status {
+			task.SetStatus(*requestBody.Status, primitive.NewDateTimeFromTime(time.Now()))
+			setStatusFields(task, changes, unset)
+		}
+		changes["status"] = task.Status
+	}
+	if requestBody.Priority != nil {
+		task.Priority = *requestBody.Priority
+		changes["priority"] = task.Priority
+	}
+	if requestBody.Labels != nil {
+		task.Labels = *requestBody.Labels
+		changes["labels"] = task.Labels
+	}
+
+	// Tasks can only be moved to projects where the user can create tasks.
+	// A zero projectId makes the task personal, which only its creator can do.
+	if requestBody.ProjectID != nil {
+		moved := task
+		moved.ProjectID = nil
+		if !requestBody.ProjectID.IsZero() {
+			moved.ProjectID = requestBody.ProjectID
+		}
+
+		if !moved.SameProject(task) {
+			// Subtasks always live in the project of their parent
+			if task.ParentID != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Subtasks can't be moved to another project"})
+				return
+			}
+			if !checkCanMove(c, task) {
+				return
+			}
+
+			access, ok := getTaskAccess(c)
+			if !ok {
+				return
+			}
+			if moved.ProjectID == nil {
+				if task.CreatedBy != access.UserID {
+					c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator can make a task personal"})
+					return
+				}
+				unset["projectId"] = ""
+			} else {
+				if !access.checkProjectPermission(c, *moved.ProjectID, models.PermTaskCreate) {
+					return
+				}
+				changes["projectId"] = *moved.ProjectID
+			}
+			task.ProjectID = moved.ProjectID
+		}
+	}
+
+	// Dates set to null are removed from the document
+	if requestBody.StartDate.Set {
+		task.StartDate = requestBody.StartDate.Value
+		if task.StartDate == nil {
+			unset["startDate"] = ""
+		} else {
+			changes["startDate"] = task.StartDate
+		}
+	}
+	if requestBody.DueDate.Set {
+		task.DueDate = requestBody.DueDate.Value
+		if task.DueDate == nil {
+			unset["dueDate"] = ""
+		} else {
+			changes["dueDate"] = task.DueDate
+		}
+	}
+
+	if len(changes) == 0 && len(unset) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
 		return
 	}
 
-	// Filter by taskID AND createdBy to ensure only own tasks are deleted
-	filter := bson.M{
-		"_id":       taskID,
-		"createdBy": userObjectID,
+	project, ok := loadTaskProject(c, task)
+	if !ok {
+		return
 	}
+	workflow := project.TaskWorkflow()
 
-	result, err := collection.DeleteOne(context.TODO(), filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+	if err := validateTask(&task, project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if requestBody.Labels != nil {
+		changes["labels"] = task.Labels // normalized by validateTask
+	}
+	if requestBody.Priority != nil {
+		changes["priorityRank"] = task.PriorityRank // set by validateTask
+	}
+	if _, ok := changes["assignees"]; ok {
+		// Normalized by validateTask
+		changes["assignees"] = task.Assignees
+		changes["assignedTo"] = task.AssignedTo
+	}
 
-	if result.DeletedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found or you don't have permission to delete it"})
+	// New assignees, and all of them when moving to another project, must
+	// be valid for the task's project
+	_, assigneesChanged := changes["assignees"]
+	if (assigneesChanged || !task.SameProject(previous)) && !checkAssignees(c, task, project) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
-}
+	// Within the same project status changes follow the workflow transitions
+	if task.SameProject(previous) && !workflow.CanTransition(previous.Status, task.Status) {
+		illegalTransition(c, previous.Status, task.Status)
+		return
+	}
 
-// UpdateTaskStatus updates a task status (pendiente, en_progreso, completada)
-func UpdateTaskStatus(c *gin.Context) {
-	taskID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if !checkBlockersDone(c, previous, task.Status) {
+		return
+	}
+
+	task.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	changes["updatedAt"] = task.UpdatedAt
+
+	collection := getTasksCollection(c)
+
+	// Only apply the update if nobody changed the task since it was loaded
+	filter := andFilters(bson.M{"_id": task.ID}, versionFilter(task.Version))
+	update := bson.M{"$set": changes, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := collection.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		updateConflict(c, task.ID)
 		return
 	}
 
-	// Get the authenticated user ID
-	userID := c.MustGet("userID").(string)
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	recordTaskChange(c, models.ActivityTaskUpdated, previous, task)
+
+	task.Version++
+	setTaskETag(c, task)
+	c.JSON(http.StatusOK, taskResult(task))
+}
+
+// checkCanMove rejects moving a task that still has subtasks or
+// dependencies, which would then span two projects
+func checkCanMove(c *gin.Context, task models.Task) bool {
+	if len(task.BlockedBy) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Remove the task's dependencies first"})
+		return false
+	}
+
+	count, err := getTasksCollection(c).CountDocuments(context.TODO(), bson.M{"$or": bson.A{
+		bson.M{"parentId": task.ID},
+		bson.M{"blockedBy": task.ID},
+	}})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check related tasks"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Move or unlink the subtasks and dependent tasks first"})
+		return false
+	}
+	return true
+}
+
+// setStatusFields adds the fields changed by Task.SetStatus to the $set and
+// $unset documents of an update. Only call it when the status changed.
+func setStatusFields(task models.Task, set, unset bson.M) {
+	set["status"] = task.Status
+	if task.CompletedAt != nil {
+		set["completedAt"] = task.CompletedAt
 		return
 	}
+	unset["completedAt"] = ""
+	unset["archived"] = ""
+	unset["archivedAt"] = ""
+}
+
+// illegalTransition writes the error for a status change the workflow forbids
+func illegalTransition(c *gin.Context, from, to models.TaskStatus) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error": "Transition from " + string(from) + " to " + string(to) + " is not allowed",
+	})
+}
+
+// UpdateTaskStatus updates a task status following the project workflow
+// Requires task:status on the task
+func UpdateTaskStatus(c *gin.Context) {
+	task := middleware.CurrentTask(c)
 
 	// Get status from request body
 	var requestBody struct {
-		Status string `json:"status" binding:"required"`
+		Status models.TaskStatus `json:"status" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
@@ -254,46 +856,70 @@ func UpdateTaskStatus(c *gin.Context) {
 		return
 	}
 
-	// Validate status values
-	if requestBody.Status != "pendiente" && requestBody.Status != "en_progreso" && requestBody.Status != "completada" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid values: pendiente, en_progreso, completada"})
+	project, ok := loadTaskProject(c, task)
+	if !ok {
 		return
 	}
+	workflow := project.TaskWorkflow()
 
-	collection := getTasksCollection(c)
-	if collection == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to tasks collection"})
+	// Only the status changes here, so the stored priority isn't checked
+	if err := workflow.ValidateStatus(requestBody.Status); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !workflow.CanTransition(task.Status, requestBody.Status) {
+		illegalTransition(c, task.Status, requestBody.Status)
+		return
+	}
+
+	if !checkBlockersDone(c, task, requestBody.Status) {
 		return
 	}
 
-	// Update task status and updatedAt - Only if the task belongs to the authenticated user
-	update := bson.M{
-		"$set": bson.M{
-			"status":    requestBody.Status,
-			"updatedAt": primitive.NewDateTimeFromTime(time.Now()),
-		},
+	// Reject the change if the client saw an older version
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	collection := getTasksCollection(c)
+
+	// Update task status, updatedAt and version
+	now := primitive.NewDateTimeFromTime(time.Now())
+	set, unset := bson.M{"updatedAt": now}, bson.M{}
+	previous := task
+	if requestBody.Status != task.Status {
+		task.SetStatus(requestBody.Status, now)
+		setStatusFields(task, set, unset)
 	}
 
-	// Filter by taskID AND createdBy to ensure only own tasks are updated
-	filter := bson.M{
-		"_id":       taskID,
-		"createdBy": userObjectID,
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
 	}
 
+	// Only apply the update if nobody changed the task since it was loaded
+	filter := andFilters(bson.M{"_id": task.ID}, versionFilter(task.Version))
+
 	result, err := collection.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
 		return
 	}
 
-	// Check if task was found and updated
+	// Check if task was changed concurrently or removed
 	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found or you don't have permission to update it"})
+		updateConflict(c, task.ID)
 		return
 	}
 
+	recordTaskChange(c, models.ActivityTaskUpdated, previous, task)
+
+	task.Version++
+	setTaskETag(c, task)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Task status updated successfully",
 		"status":  requestBody.Status,
+		"version": task.Version,
 	})
 }
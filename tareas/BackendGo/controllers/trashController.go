@@ -0,0 +1,343 @@
+package controllers
+
+import (
+	"context"
+	"go-template/middleware"
+	"go-template/models"
+	"go-template/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ------------------- Trash -------------------
+// Deleting a task or comment only marks it with deletedAt/deletedBy. Items
+// deleted along with a task (its subtasks and comments) or a comment (its
+// replies) also get deletedWith so restoring that item brings them back.
+// Deleted items are purged for good by services.StartTrashPurge once the
+// retention window has passed.
+
+// notDeleted matches the tasks or comments that aren't in the trash
+func notDeleted() bson.M {
+	return bson.M{"deletedAt": bson.M{"$exists": false}}
+}
+
+// restoreFields clears the soft delete fields
+var restoreFields = bson.M{"deletedAt": "", "deletedBy": "", "deletedWith": ""}
+
+// taskRemoval reports what was deleted or restored along with a task
+type taskRemoval struct {
+	Subtasks int64 `json:"subtasks"` // subtasks at any depth
+	Comments int64 `json:"comments"` // comments on the task and its subtasks
+}
+
+// GetTrash lists the tasks and comments the authenticated user deleted that
+// can still be restored. Items deleted along with a task are restored with
+// it and aren't listed on their own.
+func GetTrash(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"deletedBy": userID, "deletedWith": bson.M{"$exists": false}}
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+
+	trash := models.Trash{Tasks: []models.TrashedTask{}, Comments: []models.TrashedComment{}}
+
+	cursor, err := getTasksCollection(c).Find(context.TODO(), filter, opts)
+	if err == nil {
+		err = cursor.All(context.TODO(), &trash.Tasks)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deleted tasks"})
+		return
+	}
+
+	cursor, err = getCommentsCollection(c).Find(context.TODO(), filter, opts)
+	if err == nil {
+		err = cursor.All(context.TODO(), &trash.Comments)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deleted comments"})
+		return
+	}
+
+	for i := range trash.Tasks {
+		trash.Tasks[i].PurgeAt = services.PurgeTime(*trash.Tasks[i].DeletedAt)
+	}
+	for i := range trash.Comments {
+		trash.Comments[i].PurgeAt = services.PurgeTime(*trash.Comments[i].DeletedAt)
+	}
+
+	c.JSON(http.StatusOK, trash)
+}
+
+// RestoreTask takes a task out of the trash together with the subtasks and
+// comments that were deleted with it
+// Requires task:delete on the task
+func RestoreTask(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	if !task.IsDeleted() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is not in the trash"})
+		return
+	}
+	if task.DeletedWith != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task was deleted along with another task, restore that one instead", "deletedWith": task.DeletedWith})
+		return
+	}
+
+	// A subtask can't come back under a deleted parent
+	if task.ParentID != nil {
+		count, err := getTasksCollection(c).CountDocuments(context.TODO(), andFilters(bson.M{"_id": *task.ParentID}, notDeleted()))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check parent task"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Restore the parent task first"})
+			return
+		}
+	}
+
+	err := services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		tasks := getTasksCollection(c)
+		result, err := tasks.UpdateOne(sc,
+			bson.M{"_id": task.ID, "deletedAt": bson.M{"$exists": true}},
+			bson.M{"$unset": restoreFields, "$inc": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		deletedWith := bson.M{"deletedWith": task.ID}
+		subtasks, err := findTaskActivities(sc, c, deletedWith, models.ActivityTaskRestored)
+		if err != nil {
+			return err
+		}
+		comments, err := findCommentActivities(sc, c, deletedWith, models.ActivityCommentRestored)
+		if err != nil {
+			return err
+		}
+
+		_, err = tasks.UpdateMany(sc, deletedWith, bson.M{"$unset": restoreFields, "$inc": bson.M{"version": 1}})
+		if err != nil {
+			return err
+		}
+		_, err = getCommentsCollection(c).UpdateMany(sc, deletedWith, bson.M{"$unset": restoreFields})
+		if err != nil {
+			return err
+		}
+
+		activities := append([]models.Activity{{TaskID: task.ID, Action: models.ActivityTaskRestored}}, subtasks...)
+		return recordActivities(sc, c, append(activities, comments...))
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "Task is not in the trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore task"})
+		return
+	}
+
+	task.SoftDelete = models.SoftDelete{}
+	task.Version++
+	setTaskETag(c, task)
+	c.JSON(http.StatusOK, taskResult(task))
+}
+
+// RestoreComment takes a comment out of the trash together with the replies
+// that were deleted with it
+// Requires task:read on the comment's task; like deleting, only the author
+// or roles with comment:delete can restore it
+func RestoreComment(c *gin.Context) {
+	comment := middleware.CurrentComment(c)
+	task := middleware.CurrentTask(c)
+
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	if comment.AuthorID != userID && !middleware.HasPermission(c, models.PermCommentDelete) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only restore your own comments"})
+		return
+	}
+
+	if task.IsDeleted() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Restore the task first"})
+		return
+	}
+	if comment.DeletedWith != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Comment was deleted along with the comment it replies to, restore that one instead", "deletedWith": comment.DeletedWith})
+		return
+	}
+
+	// A reply can't come back under a deleted comment
+	if comment.ParentID != nil {
+		count, err := getCommentsCollection(c).CountDocuments(context.TODO(), andFilters(bson.M{"_id": *comment.ParentID}, notDeleted()))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check parent comment"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Restore the comment it replies to first"})
+			return
+		}
+	}
+
+	err := services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		comments := getCommentsCollection(c)
+		result, err := comments.UpdateOne(sc,
+			bson.M{"_id": comment.ID, "deletedAt": bson.M{"$exists": true}},
+			bson.M{"$unset": restoreFields},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		deletedWith := bson.M{"deletedWith": comment.ID}
+		replies, err := findCommentActivities(sc, c, deletedWith, models.ActivityCommentRestored)
+		if err != nil {
+			return err
+		}
+		if _, err := comments.UpdateMany(sc, deletedWith, bson.M{"$unset": restoreFields}); err != nil {
+			return err
+		}
+
+		activities := append([]models.Activity{commentActivity(comment, models.ActivityCommentRestored)}, replies...)
+		return recordActivities(sc, c, activities)
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "Comment is not in the trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore comment"})
+		return
+	}
+
+	comment.SoftDelete = models.SoftDelete{}
+	c.JSON(http.StatusOK, comment.View(userID))
+}
+
+// trashTaskTree moves a task, its subtasks and their comments to the trash
+// and records it in their history. Items that were already deleted keep
+// their own deletion. It must run inside a transaction and returns
+// mongo.ErrNoDocuments if the task is already in the trash.
+func trashTaskTree(c *gin.Context, sc mongo.SessionContext, taskID, userID primitive.ObjectID) (taskRemoval, error) {
+	var removed taskRemoval
+	tasks := getTasksCollection(c)
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	// Collect the live subtasks level by level
+	var subtasks []primitive.ObjectID
+	for parents := []primitive.ObjectID{taskID}; len(parents) > 0; {
+		cursor, err := tasks.Find(sc,
+			andFilters(bson.M{"parentId": bson.M{"$in": parents}}, notDeleted()),
+			options.Find().SetProjection(bson.M{"_id": 1}),
+		)
+		if err != nil {
+			return removed, err
+		}
+		var children []models.Task
+		if err := cursor.All(sc, &children); err != nil {
+			return removed, err
+		}
+
+		parents = nil
+		for _, child := range children {
+			parents = append(parents, child.ID)
+		}
+		subtasks = append(subtasks, parents...)
+	}
+
+	result, err := tasks.UpdateOne(sc,
+		andFilters(bson.M{"_id": taskID}, notDeleted()),
+		bson.M{"$set": bson.M{"deletedAt": now, "deletedBy": userID}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return removed, err
+	}
+	if result.MatchedCount == 0 {
+		return removed, mongo.ErrNoDocuments
+	}
+
+	deletedWith := bson.M{"deletedAt": now, "deletedBy": userID, "deletedWith": taskID}
+	activities := []models.Activity{{TaskID: taskID, Action: models.ActivityTaskDeleted}}
+
+	if len(subtasks) > 0 {
+		for _, id := range subtasks {
+			activities = append(activities, models.Activity{TaskID: id, Action: models.ActivityTaskDeleted})
+		}
+		result, err = tasks.UpdateMany(sc,
+			andFilters(bson.M{"_id": bson.M{"$in": subtasks}}, notDeleted()),
+			bson.M{"$set": deletedWith, "$inc": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return removed, err
+		}
+		removed.Subtasks = result.ModifiedCount
+	}
+
+	commentsFilter := andFilters(bson.M{"taskId": bson.M{"$in": append(subtasks, taskID)}}, notDeleted())
+	comments, err := findCommentActivities(sc, c, commentsFilter, models.ActivityCommentDeleted)
+	if err != nil {
+		return removed, err
+	}
+	result, err = getCommentsCollection(c).UpdateMany(sc, commentsFilter, bson.M{"$set": deletedWith})
+	if err != nil {
+		return removed, err
+	}
+	removed.Comments = result.ModifiedCount
+
+	return removed, recordActivities(sc, c, append(activities, comments...))
+}
+
+// findTaskActivities builds an activity for each task matching filter
+func findTaskActivities(ctx context.Context, c *gin.Context, filter bson.M, action string) ([]models.Activity, error) {
+	cursor, err := getTasksCollection(c).Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+
+	activities := make([]models.Activity, 0, len(tasks))
+	for _, task := range tasks {
+		activities = append(activities, models.Activity{TaskID: task.ID, Action: action})
+	}
+	return activities, nil
+}
+
+// findCommentActivities builds an activity for each comment matching filter
+func findCommentActivities(ctx context.Context, c *gin.Context, filter bson.M, action string) ([]models.Activity, error) {
+	cursor, err := getCommentsCollection(c).Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "taskId": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var comments []models.Comment
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	activities := make([]models.Activity, 0, len(comments))
+	for _, comment := range comments {
+		activities = append(activities, commentActivity(comment, action))
+	}
+	return activities, nil
+}
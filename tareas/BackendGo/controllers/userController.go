@@ -1,18 +1,23 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"go-template/models"
 	"go-template/services"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // getUserCollection returns the MongoDB users collection
 func getUserCollection(c *gin.Context) *mongo.Collection {
-	return services.Client.Database("task_db").Collection("users")
+	return services.DB.Collection("users")
 }
 
 // UserMe retrieves the authenticated user's profile information
@@ -27,7 +32,7 @@ func UserMe(c *gin.Context) {
 	}
 
 	users := getUserCollection(c)
-	var userData map[string]interface{}
+	var userData models.User
 
 	// Use ObjectID instead of string
 	err = users.FindOne(c, bson.M{"_id": objectID}).Decode(&userData)
@@ -40,7 +45,7 @@ func UserMe(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{"user": userData})
+	c.JSON(200, gin.H{"user": userData.Public()})
 }
 
 // GetAllUsers retrieves all users from the database
@@ -53,11 +58,307 @@ func GetAllUsers(c *gin.Context) {
 	}
 	defer cursor.Close(c)
 
-	var userList []map[string]interface{}
+	var userList []models.User
 	if err = cursor.All(c, &userList); err != nil {
 		c.JSON(500, gin.H{"error": "Failed to decode user data"})
 		return
 	}
 
-	c.JSON(200, gin.H{"users": userList})
+	publicUsers := make([]models.PublicUser, 0, len(userList))
+	for _, user := range userList {
+		publicUsers = append(publicUsers, user.Public())
+	}
+
+	c.JSON(200, gin.H{"users": publicUsers})
+}
+
+// DeleteUserMe deletes the authenticated user's account. Users who still own
+// projects must transfer or delete them first, and so must users who created
+// personal tasks assigned to others, or who are the only assignee of
+// personal tasks nobody else can take over. Their
+// assignments are released following unassignUser, and their memberships
+// and sessions are removed. Tasks and comments they wrote are kept.
+func DeleteUserMe(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	owned, err := getProjectsCollection(c).CountDocuments(context.TODO(), bson.M{"ownerId": userID})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to check projects"})
+		return
+	}
+	if owned > 0 {
+		c.JSON(409, gin.H{"error": "Delete your projects before deleting your account"})
+		return
+	}
+
+	// Nobody else could edit or delete personal tasks they share
+	shared, err := sharedPersonalTasks(c, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to check tasks"})
+		return
+	}
+	if len(shared) > 0 {
+		c.JSON(409, gin.H{"error": "Delete or unassign others from your personal tasks before deleting your account", "tasks": shared})
+		return
+	}
+
+	if err := unassignUser(c, userID, nil); err != nil {
+		var noFallback *noFallbackError
+		if errors.As(err, &noFallback) {
+			c.JSON(409, gin.H{"error": "Delete or reassign your personal tasks before deleting your account", "tasks": noFallback.Tasks})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to release assigned tasks"})
+		return
+	}
+
+	_, err = getProjectsCollection(c).UpdateMany(context.TODO(),
+		bson.M{"members.userId": userID},
+		bson.M{"$pull": bson.M{"members": bson.M{"userId": userID}}},
+	)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to remove project memberships"})
+		return
+	}
+
+	if _, err := getRefreshTokenCollection(c).DeleteMany(context.TODO(), bson.M{"userId": userID}); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	result, err := getUserCollection(c).DeleteOne(context.TODO(), bson.M{"_id": userID})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(404, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "User deleted successfully"})
+}
+
+// sharedPersonalTasks returns the live personal tasks created by the user
+// that are assigned to someone else
+func sharedPersonalTasks(c *gin.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := getTasksCollection(c).Find(context.TODO(),
+		andFilters(bson.M{
+			"createdBy": userID,
+			"projectId": bson.M{"$exists": false},
+			"assignees": bson.M{"$elemMatch": bson.M{"$ne": userID}},
+		}, notDeleted()),
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	if err := cursor.All(context.TODO(), &tasks); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids, nil
+}
+
+// noFallbackError is returned by unassignUser when the user is the only
+// assignee of tasks nobody else can take over
+type noFallbackError struct {
+	Tasks []primitive.ObjectID
+}
+
+func (e *noFallbackError) Error() string {
+	return "no fallback assignee for " + strconv.Itoa(len(e.Tasks)) + " tasks"
+}
+
+// unassignUser removes a user from the assignees and watchers of the tasks
+// matching scope (nil for every task). This is the unassign policy for users
+// who are deleted or leave a project: a task left without assignees goes to
+// its creator if they can still be assigned to it (checkAssignees), or else
+// to the project owner. Personal tasks have no owner to fall back to, so if
+// the creator can't take one back nothing is changed and a *noFallbackError
+// lists the tasks. Tasks in the trash that can't be handed over keep the
+// user until they are purged. Changes are recorded in the tasks' history
+// as made by the authenticated user.
+func unassignUser(c *gin.Context, userID primitive.ObjectID, scope bson.M) error {
+	tasks := getTasksCollection(c)
+
+	cursor, err := tasks.Find(context.TODO(), andFilters(scope, bson.M{"assignees": userID}))
+	if err != nil {
+		return err
+	}
+	var assigned []models.Task
+	if err := cursor.All(context.TODO(), &assigned); err != nil {
+		return err
+	}
+
+	fallbacks, err := fallbackAssignees(c, userID, assigned)
+	if err != nil {
+		return err
+	}
+
+	var blocked []primitive.ObjectID
+	for _, task := range assigned {
+		if _, ok := fallbacks[task.ID]; !ok && len(task.Assignees) == 1 && !task.IsDeleted() {
+			blocked = append(blocked, task.ID)
+		}
+	}
+	if len(blocked) > 0 {
+		return &noFallbackError{Tasks: blocked}
+	}
+
+	return services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		var activities []models.Activity
+		withoutUser := func(field string) bson.M {
+			return bson.M{"$filter": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}},
+				"cond":  bson.M{"$ne": bson.A{"$$this", userID}},
+			}}
+		}
+
+		for _, task := range assigned {
+			filter := bson.M{"_id": task.ID, "assignees": userID}
+			fallback, ok := fallbacks[task.ID]
+			if !ok {
+				if len(task.Assignees) == 1 {
+					continue
+				}
+				// Without a fallback, only update if someone else is still assigned
+				filter["assignees.1"] = bson.M{"$exists": true}
+			}
+
+			// The assignees are recomputed in the update so concurrent edits aren't lost
+			var updated models.Task
+			err := tasks.FindOneAndUpdate(sc,
+				filter,
+				mongo.Pipeline{
+					{{Key: "$set", Value: bson.M{"assignees": withoutUser("assignees"), "watchers": withoutUser("watchers")}}},
+					{{Key: "$set", Value: bson.M{
+						"assignees": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$size": "$assignees"}, 0}}, bson.A{fallback}, "$assignees"}},
+					}}},
+					{{Key: "$set", Value: bson.M{
+						"assignedTo": bson.M{"$arrayElemAt": bson.A{"$assignees", 0}},
+						"version":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+						"updatedAt":  "$$NOW",
+					}}},
+				},
+				options.FindOneAndUpdate().SetReturnDocument(options.After),
+			).Decode(&updated)
+			if err == mongo.ErrNoDocuments {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			activities = append(activities, models.Activity{TaskID: task.ID, Action: models.ActivityTaskUpdated, Changes: diffTasks(task, updated)})
+		}
+
+		// Tasks the user only watches
+		watchedFilter := andFilters(scope, bson.M{"watchers": userID})
+		cursor, err := tasks.Find(sc, watchedFilter, options.Find().SetProjection(bson.M{"watchers": 1}))
+		if err != nil {
+			return err
+		}
+		var watched []models.Task
+		if err := cursor.All(sc, &watched); err != nil {
+			return err
+		}
+		if _, err := tasks.UpdateMany(sc, watchedFilter, bson.M{"$pull": bson.M{"watchers": userID}}); err != nil {
+			return err
+		}
+		for _, task := range watched {
+			changed := task
+			changed.Watchers = nil
+			for _, id := range task.Watchers {
+				if id != userID {
+					changed.Watchers = append(changed.Watchers, id)
+				}
+			}
+			activities = append(activities, models.Activity{TaskID: task.ID, Action: models.ActivityTaskUpdated, Changes: diffTasks(task, changed)})
+		}
+
+		return recordActivities(sc, c, activities)
+	})
+}
+
+// fallbackAssignees picks who takes over each task the user is the only
+// assignee of. Tasks without a valid fallback are left out of the result.
+func fallbackAssignees(c *gin.Context, userID primitive.ObjectID, assigned []models.Task) (map[primitive.ObjectID]primitive.ObjectID, error) {
+	var creatorIDs, projectIDs []primitive.ObjectID
+	for _, task := range assigned {
+		if len(task.Assignees) == 1 {
+			creatorIDs = append(creatorIDs, task.CreatedBy)
+			if task.ProjectID != nil {
+				projectIDs = append(projectIDs, *task.ProjectID)
+			}
+		}
+	}
+	fallbacks := map[primitive.ObjectID]primitive.ObjectID{}
+	if len(creatorIDs) == 0 {
+		return fallbacks, nil
+	}
+
+	cursor, err := getUserCollection(c).Find(context.TODO(),
+		bson.M{"_id": bson.M{"$in": creatorIDs}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := cursor.All(context.TODO(), &users); err != nil {
+		return nil, err
+	}
+	existing := map[primitive.ObjectID]bool{}
+	for _, user := range users {
+		existing[user.ID] = true
+	}
+
+	projects := map[primitive.ObjectID]models.Project{}
+	if len(projectIDs) > 0 {
+		cursor, err := getProjectsCollection(c).Find(context.TODO(), bson.M{"_id": bson.M{"$in": projectIDs}})
+		if err != nil {
+			return nil, err
+		}
+		var found []models.Project
+		if err := cursor.All(context.TODO(), &found); err != nil {
+			return nil, err
+		}
+		for _, project := range found {
+			projects[project.ID] = project
+		}
+	}
+
+	for _, task := range assigned {
+		if len(task.Assignees) != 1 {
+			continue
+		}
+		creator := task.CreatedBy
+		creatorValid := creator != userID && existing[creator]
+
+		if task.ProjectID == nil {
+			if creatorValid {
+				fallbacks[task.ID] = creator
+			}
+			continue
+		}
+
+		project, ok := projects[*task.ProjectID]
+		switch {
+		case !ok:
+		case creatorValid && project.RoleOf(creator) != "":
+			fallbacks[task.ID] = creator
+		case project.OwnerID != userID:
+			fallbacks[task.ID] = project.OwnerID
+		}
+	}
+
+	return fallbacks, nil
 }
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-template/middleware"
+	"go-template/models"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ------------------- Watcher Controller Functions -------------------
+// Watchers follow a task without being assigned to it. Following a task
+// doesn't grant access to it, so only users who can read the task can be
+// watchers. Watching isn't an edit, so it doesn't change the task version.
+
+// AddTaskWatcher adds a watcher to the task in :id. Without a userId in the
+// body the authenticated user starts watching the task.
+// Requires task:read on the task; adding someone else requires task:update
+func AddTaskWatcher(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	var requestBody struct {
+		UserID primitive.ObjectID `json:"userId"`
+	}
+	// The body is optional
+	if err := c.ShouldBindJSON(&requestBody); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	watcherID, ok := resolveWatcher(c, requestBody.UserID)
+	if !ok {
+		return
+	}
+
+	// The watcher must be able to read the task
+	project, _ := middleware.CurrentProject(c)
+	if len(task.RolesOf(watcherID, project)) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The user can't access this task"})
+		return
+	}
+
+	updateWatchers(c, task.ID, bson.M{"$addToSet": bson.M{"watchers": watcherID}})
+}
+
+// RemoveTaskWatcher removes the user in :userId from the watchers of the task
+// Requires task:read on the task; removing someone else requires task:update
+func RemoveTaskWatcher(c *gin.Context) {
+	task := middleware.CurrentTask(c)
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	watcherID, ok := resolveWatcher(c, userID)
+	if !ok {
+		return
+	}
+
+	if !task.IsWatcher(watcherID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watcher not found"})
+		return
+	}
+
+	updateWatchers(c, task.ID, bson.M{"$pull": bson.M{"watchers": watcherID}})
+}
+
+// resolveWatcher returns the user whose watch is being changed, defaulting
+// to the authenticated user. Changing another user needs task:update.
+func resolveWatcher(c *gin.Context, userID primitive.ObjectID) (primitive.ObjectID, bool) {
+	authID, ok := getAuthUserID(c)
+	if !ok {
+		return authID, false
+	}
+	if userID.IsZero() || userID == authID {
+		return authID, true
+	}
+
+	if !middleware.HasPermission(c, models.PermTaskUpdate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do this (" + models.PermTaskUpdate + ")"})
+		return userID, false
+	}
+	return userID, true
+}
+
+// updateWatchers applies a change to the watchers of a task and writes the new list
+func updateWatchers(c *gin.Context, taskID primitive.ObjectID, update bson.M) {
+	var updated models.Task
+	err := getTasksCollection(c).FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": taskID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"watchers": 1}),
+	).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update watchers"})
+		return
+	}
+
+	// Only the watchers were loaded, so compare them on the task in the context
+	previous := middleware.CurrentTask(c)
+	current := previous
+	current.Watchers = updated.Watchers
+	recordTaskChange(c, models.ActivityTaskUpdated, previous, current)
+
+	watchers := updated.Watchers
+	if watchers == nil {
+		watchers = []primitive.ObjectID{}
+	}
+	c.JSON(http.StatusOK, gin.H{"watchers": watchers})
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+    "go-template/config"
+    "go-template/middleware"
     "go-template/routes"
     "go-template/services"
+    "log"
     "time"
 
     "github.com/gin-contrib/cors"
@@ -10,16 +13,28 @@ import (
 )
 
 func main() {
+    // Cargar configuración
+    cfg, err := config.Load()
+    if err != nil {
+        log.Fatal("Invalid configuration:\n", err)
+    }
+
+    middleware.Configure(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+
     // Inicializar conexión Mongo
-    services.InitMongo()
+    services.InitMongo(cfg.MongoURI, cfg.DatabaseName)
+    services.EnsureIndexes()
+    services.MigrateData()
+    services.StartTrashPurge(cfg.TrashRetention, cfg.TrashPurgeEvery)
+    services.StartAutoArchive(cfg.AutoArchiveDays)
 
     r := gin.Default()
 
     r.Use(cors.New(cors.Config{
-        AllowOrigins:     []string{"http://localhost:3000"},
+        AllowOrigins:     cfg.CORSOrigins,
         AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-        ExposeHeaders:    []string{"Content-Length"},
+        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "If-Match"},
+        ExposeHeaders:    []string{"Content-Length", "ETag", "X-Total-Count", "X-Next-Cursor"},
         AllowCredentials: true,
         MaxAge:           12 * time.Hour,
     }))
@@ -27,6 +42,9 @@ func main() {
     // Registrar rutas
     routes.RegisterRoutes(r)
     routes.RoutesAuth(r)
+    routes.RoutesProject(r)
 
-    r.Run(":8080")
+    if err := r.Run(cfg.Addr()); err != nil {
+        log.Fatal("Server error:", err)
+    }
 }
\ No newline at end of file
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"context"
+	"go-template/models"
+	"go-template/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Context keys set by RequirePermission
+const (
+	taskKey    = "task"
+	commentKey = "comment"
+	projectKey = "project"
+	rolesKey   = "roles"
+
+	allowDeletedKey = "allowDeleted"
+)
+
+// AllowDeleted lets the following RequirePermission load tasks and comments
+// that are in the trash, which are reported as not found otherwise. Used by
+// the restore endpoints.
+func AllowDeleted() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(allowDeletedKey, true)
+		c.Next()
+	}
+}
+
+// RequirePermission authorizes the request against the resource in the
+// route. Permissions starting with "project:" are checked on the project in
+// :id. The others are checked on the task in :id, or on the task of the
+// comment in :commentId.
+//
+// The caller's roles come from their project membership plus the implicit
+// roles of the task itself: the creator owns a task outside any project and
+// the assignee can always read, move and comment on it. Resources the user
+// has no role on are reported as 404 so their existence isn't leaked; known
+// resources without the permission get a 403.
+//
+// Must run after AuthMiddleware. On success the loaded task, comment and
+// project are available through CurrentTask, CurrentComment and CurrentProject.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+
+		var roles []string
+		if strings.HasPrefix(permission, "project:") {
+			roles = authorizeProject(c, userID)
+		} else {
+			roles = authorizeTask(c, userID)
+		}
+		if c.IsAborted() {
+			return
+		}
+
+		for _, role := range roles {
+			if models.RoleAllows(role, permission) {
+				c.Set(rolesKey, roles)
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do this (" + permission + ")"})
+	}
+}
+
+// authorizeProject loads the project in :id and returns the user's role on it
+func authorizeProject(c *gin.Context, userID primitive.ObjectID) []string {
+	projectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil
+	}
+
+	project, ok := findProject(c, projectID)
+	if !ok {
+		return nil
+	}
+
+	role := project.RoleOf(userID)
+	if role == "" {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil
+	}
+
+	c.Set(projectKey, project)
+	return []string{role}
+}
+
+// authorizeTask loads the task in :id (or the task of the comment in
+// :commentId) and returns every role the user holds on it
+func authorizeTask(c *gin.Context, userID primitive.ObjectID) []string {
+	var taskID primitive.ObjectID
+	if commentParam := c.Param("commentId"); commentParam != "" {
+		commentID, err := primitive.ObjectIDFromHex(commentParam)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+			return nil
+		}
+
+		var comment models.Comment
+		err = services.DB.Collection("comments").FindOne(context.TODO(), bson.M{"_id": commentID}).Decode(&comment)
+		if err != nil {
+			abortLookup(c, err, "Comment not found")
+			return nil
+		}
+		if comment.IsDeleted() && !c.GetBool(allowDeletedKey) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return nil
+		}
+		c.Set(commentKey, comment)
+		taskID = comment.TaskID
+	} else {
+		var err error
+		taskID, err = primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+			return nil
+		}
+	}
+
+	var task models.Task
+	err := services.DB.Collection("tasks").FindOne(context.TODO(), bson.M{"_id": taskID}).Decode(&task)
+	if err != nil {
+		abortLookup(c, err, "Task not found")
+		return nil
+	}
+	if task.IsDeleted() && !c.GetBool(allowDeletedKey) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return nil
+	}
+
+	var project models.Project
+	if task.ProjectID != nil {
+		var ok bool
+		project, ok = findProject(c, *task.ProjectID)
+		if !ok {
+			return nil
+		}
+		c.Set(projectKey, project)
+	}
+
+	roles := task.RolesOf(userID, project)
+
+	if len(roles) == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return nil
+	}
+
+	c.Set(taskKey, task)
+	return roles
+}
+
+// findProject loads a project by ID, aborting the request if it can't
+func findProject(c *gin.Context, projectID primitive.ObjectID) (models.Project, bool) {
+	var project models.Project
+	err := services.DB.Collection("projects").FindOne(context.TODO(), bson.M{"_id": projectID}).Decode(&project)
+	if err != nil {
+		abortLookup(c, err, "Project not found")
+		return project, false
+	}
+	return project, true
+}
+
+// abortLookup aborts with 404 for missing documents and 500 for other errors
+func abortLookup(c *gin.Context, err error, notFound string) {
+	if err == mongo.ErrNoDocuments {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+}
+
+// HasPermission reports whether the roles resolved by RequirePermission
+// also grant another permission
+func HasPermission(c *gin.Context, permission string) bool {
+	roles, _ := c.Get(rolesKey)
+	list, _ := roles.([]string)
+	for _, role := range list {
+		if models.RoleAllows(role, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentTask returns the task loaded by RequirePermission
+func CurrentTask(c *gin.Context) models.Task {
+	return c.MustGet(taskKey).(models.Task)
+}
+
+// CurrentComment returns the comment loaded by RequirePermission
+func CurrentComment(c *gin.Context) models.Comment {
+	return c.MustGet(commentKey).(models.Comment)
+}
+
+// CurrentProject returns the project loaded by RequirePermission, if any
+func CurrentProject(c *gin.Context) (models.Project, bool) {
+	value, ok := c.Get(projectKey)
+	if !ok {
+		return models.Project{}, false
+	}
+	return value.(models.Project), true
+}
@@ -1,19 +1,60 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtSecret = []byte("contraseña_secreta_para_este_ejemplo")
+// Signing key and token lifetimes, set once at startup by Configure
+var (
+	jwtSecret       []byte
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+)
+
+// Configure sets the JWT signing secret and token lifetimes
+func Configure(secret string, accessTTL, refreshTTL time.Duration) {
+	jwtSecret = []byte(secret)
+	accessTokenTTL = accessTTL
+	refreshTokenTTL = refreshTTL
+}
+
+// AccessTokenTTL returns how long an access token stays valid
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
 
-// GenerateToken creates a JWT token for the given user ID with a 5-minute expiration time
+// RefreshTokenTTL returns how long a refresh token stays valid
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenTTL
+}
+
+// GenerateToken creates a JWT access token for the given user ID
 func GenerateToken(userID string) (string, error) {
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
         "userID": userID,
-        "exp":    time.Now().Add(time.Minute * 5).Unix(), 
+        "exp":    time.Now().Add(accessTokenTTL).Unix(), 
     })
 
     return token.SignedString(jwtSecret)
-}
\ No newline at end of file
+}
+
+// GenerateRefreshToken creates a random opaque refresh token
+func GenerateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashRefreshToken returns the value stored in the database for a refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
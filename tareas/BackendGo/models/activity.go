@@ -0,0 +1,36 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Activity actions
+const (
+	ActivityTaskCreated     = "task.created"
+	ActivityTaskUpdated     = "task.updated"
+	ActivityTaskDeleted     = "task.deleted"
+	ActivityTaskRestored    = "task.restored"
+	ActivityCommentCreated  = "comment.created"
+	ActivityCommentUpdated  = "comment.updated"
+	ActivityCommentDeleted  = "comment.deleted"
+	ActivityCommentRestored = "comment.restored"
+)
+
+// Activity is an entry in the history of a task: who did what and when.
+// Changes made by the server's background jobs (auto-archive, trash purge)
+// have no actor.
+type Activity struct {
+	ID        primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TaskID    primitive.ObjectID  `json:"taskId" bson:"taskId"`
+	ActorID   *primitive.ObjectID `json:"actorId,omitempty" bson:"actorId,omitempty"`
+	Action    string              `json:"action" bson:"action"`
+	Changes   []FieldChange       `json:"changes,omitempty" bson:"changes,omitempty"`
+	CommentID *primitive.ObjectID `json:"commentId,omitempty" bson:"commentId,omitempty"` // for comment actions
+	CreatedAt primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+}
+
+// FieldChange is the value of a task field before and after a change. Values
+// are stored as they appear in the task's JSON.
+type FieldChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
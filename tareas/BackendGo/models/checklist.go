@@ -0,0 +1,44 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ChecklistItem is a lightweight step embedded in a task. Unlike subtasks it
+// has no status, assignee or dates of its own.
+type ChecklistItem struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Text      string             `json:"text" bson:"text"`
+	Done      bool               `json:"done" bson:"done"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
+
+// SubtaskCount is how many subtasks a task has and how many are completed
+type SubtaskCount struct {
+	Total int `json:"total" bson:"total"`
+	Done  int `json:"done" bson:"done"`
+}
+
+// TaskProgress summarizes the completed subtasks and checklist items of a task
+type TaskProgress struct {
+	Subtasks  SubtaskCount `json:"subtasks"`
+	Checklist SubtaskCount `json:"checklist"`
+	Percent   int          `json:"percent"` // 0-100, rounded down
+}
+
+// Progress returns the progress of the task given the counts of its
+// subtasks, or nil when it has neither subtasks nor checklist items
+func (t Task) Progress(subtasks SubtaskCount) *TaskProgress {
+	progress := TaskProgress{Subtasks: subtasks}
+	for _, item := range t.Checklist {
+		progress.Checklist.Total++
+		if item.Done {
+			progress.Checklist.Done++
+		}
+	}
+
+	total := progress.Subtasks.Total + progress.Checklist.Total
+	if total == 0 {
+		return nil
+	}
+	progress.Percent = (progress.Subtasks.Done + progress.Checklist.Done) * 100 / total
+	return &progress
+}
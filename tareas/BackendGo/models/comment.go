@@ -5,9 +5,73 @@ import (
 )
 
 type Comment struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	TaskID    primitive.ObjectID `json:"taskId" bson:"taskId"`
-	AuthorID  primitive.ObjectID `json:"authorId" bson:"authorId"`
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TaskID     primitive.ObjectID  `json:"taskId" bson:"taskId"`
+	ParentID   *primitive.ObjectID `json:"parentId,omitempty" bson:"parentId,omitempty"` // comment this one replies to
+	AuthorID   primitive.ObjectID  `json:"authorId" bson:"authorId"`
+	Text       string              `json:"text" bson:"text"`
+	CreatedAt  primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	EditedAt   *primitive.DateTime `json:"editedAt,omitempty" bson:"editedAt,omitempty"`
+	Revisions  []CommentRevision   `json:"revisions,omitempty" bson:"revisions,omitempty"` // previous texts, oldest first
+	Reactions  []CommentReaction   `json:"-" bson:"reactions,omitempty"`                   // listed as counts, see View
+	SoftDelete `bson:",inline"`
+}
+
+// CommentRevision is a text a comment had before it was edited
+type CommentRevision struct {
 	Text      string             `json:"text" bson:"text"`
-	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"` // when this text was written
+}
+
+// CommentResult is a comment returned by a text search
+type CommentResult struct {
+	Comment   `bson:",inline"`
+	Score     float64 `json:"score" bson:"score"`
+	Highlight string  `json:"highlight,omitempty" bson:"-"`
+	TaskTitle string  `json:"taskTitle" bson:"taskTitle"`
+}
+
+// CommentReaction is an emoji a user reacted with
+type CommentReaction struct {
+	Emoji  string             `json:"emoji" bson:"emoji"`
+	UserID primitive.ObjectID `json:"userId" bson:"userId"`
+}
+
+// ReactionCount is how many users reacted to a comment with an emoji
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"` // whether the user viewing the comment is among them
+}
+
+// CommentView is a comment with its reactions counted for a user
+type CommentView struct {
+	Comment
+	Reactions []ReactionCount `json:"reactions"`
+}
+
+// CommentThread is a top-level comment with its replies, oldest first
+type CommentThread struct {
+	CommentView
+	Replies []CommentView `json:"replies"`
+}
+
+// View counts the reactions on the comment for the given user. Emojis are
+// listed in the order they were first used.
+func (c Comment) View(userID primitive.ObjectID) CommentView {
+	counts := []ReactionCount{}
+	index := map[string]int{}
+	for _, reaction := range c.Reactions {
+		i, ok := index[reaction.Emoji]
+		if !ok {
+			i = len(counts)
+			index[reaction.Emoji] = i
+			counts = append(counts, ReactionCount{Emoji: reaction.Emoji})
+		}
+		counts[i].Count++
+		if reaction.UserID == userID {
+			counts[i].Reacted = true
+		}
+	}
+	return CommentView{Comment: c, Reactions: counts}
 }
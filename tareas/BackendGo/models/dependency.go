@@ -0,0 +1,29 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// TaskSummary is the short form of a task used where only a reference to it
+// is needed, such as in dependency lists and graphs
+type TaskSummary struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id"`
+	Title  string             `json:"title" bson:"title"`
+	Status TaskStatus         `json:"status" bson:"status"`
+}
+
+// TaskDependencies lists the tasks blocking a task and the tasks it blocks
+type TaskDependencies struct {
+	BlockedBy []TaskSummary `json:"blockedBy"`
+	Blocks    []TaskSummary `json:"blocks"`
+}
+
+// DependencyEdge means the From task blocks the To task
+type DependencyEdge struct {
+	From primitive.ObjectID `json:"from"`
+	To   primitive.ObjectID `json:"to"`
+}
+
+// DependencyGraph is the dependency graph of a project's tasks
+type DependencyGraph struct {
+	Nodes []TaskSummary    `json:"nodes"`
+	Edges []DependencyEdge `json:"edges"`
+}
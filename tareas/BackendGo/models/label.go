@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Label categorizes tasks. Projects keep a catalog of labels with colors;
+// tasks store only the label names.
+type Label struct {
+	Name  string `json:"name" bson:"name"`
+	Color string `json:"color" bson:"color"`
+}
+
+var (
+	labelNamePattern  = regexp.MustCompile(`^[\p{Ll}\p{N}][\p{Ll}\p{N} _-]{0,31}$`)
+	labelColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+)
+
+// DefaultLabelColor is used for labels created without a color
+const DefaultLabelColor = "#9e9e9e"
+
+// NormalizeLabelName trims and lowercases a label name so "Bug" and "bug "
+// are the same label
+func NormalizeLabelName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Normalize cleans up the label's name and color and checks they are valid
+func (l *Label) Normalize() error {
+	l.Name = NormalizeLabelName(l.Name)
+	if !labelNamePattern.MatchString(l.Name) {
+		return fmt.Errorf("Invalid label name %q. Use up to 32 letters, digits, spaces, dashes or underscores", l.Name)
+	}
+
+	l.Color = strings.ToLower(strings.TrimSpace(l.Color))
+	if l.Color == "" {
+		l.Color = DefaultLabelColor
+	}
+	if !labelColorPattern.MatchString(l.Color) {
+		return fmt.Errorf("Invalid label color %q. Use a hex color like #ff0000", l.Color)
+	}
+	return nil
+}
+
+// NormalizeLabels normalizes, validates and deduplicates the labels of a task
+func NormalizeLabels(names []string) ([]string, error) {
+	seen := map[string]bool{}
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		name = NormalizeLabelName(name)
+		if !labelNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("Invalid label name %q", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			labels = append(labels, name)
+		}
+	}
+	return labels, nil
+}
+
+// HasLabel reports whether the label is in the project's catalog
+func (p Project) HasLabel(name string) bool {
+	for _, label := range p.Labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLabelNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		label   Label
+		want    Label
+		wantErr string // empty when the label is valid
+	}{
+		{"lowercases and trims", Label{Name: "  Bug ", Color: " #FF0000 "}, Label{Name: "bug", Color: "#ff0000"}, ""},
+		{"default color", Label{Name: "docs"}, Label{Name: "docs", Color: DefaultLabelColor}, ""},
+		{"spaces, dashes and underscores", Label{Name: "needs review-2_x"}, Label{Name: "needs review-2_x", Color: DefaultLabelColor}, ""},
+		{"accents", Label{Name: "Diseño"}, Label{Name: "diseño", Color: DefaultLabelColor}, ""},
+		{"starts with a digit", Label{Name: "2024"}, Label{Name: "2024", Color: DefaultLabelColor}, ""},
+		{"32 characters", Label{Name: strings.Repeat("ñ", 32)}, Label{Name: strings.Repeat("ñ", 32), Color: DefaultLabelColor}, ""},
+		{"empty name", Label{Name: "   "}, Label{}, "Invalid label name"},
+		{"33 characters", Label{Name: strings.Repeat("a", 33)}, Label{}, "Invalid label name"},
+		{"starts with a dash", Label{Name: "-bug"}, Label{}, "Invalid label name"},
+		{"symbols", Label{Name: "bug!"}, Label{}, "Invalid label name"},
+		{"short color", Label{Name: "bug", Color: "#fff"}, Label{}, "Invalid label color"},
+		{"color without #", Label{Name: "bug", Color: "ff0000"}, Label{}, "Invalid label color"},
+		{"named color", Label{Name: "bug", Color: "red"}, Label{}, "Invalid label color"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			label := tt.label
+			err := label.Normalize()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Normalize = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			if label != tt.want {
+				t.Errorf("Normalize = %+v, want %+v", label, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr bool
+	}{
+		{"nil", nil, []string{}, false},
+		{"keeps order", []string{"ui", "backend"}, []string{"ui", "backend"}, false},
+		{"deduplicates after normalizing", []string{"Bug", "bug ", "UI", "BUG"}, []string{"bug", "ui"}, false},
+		{"invalid name", []string{"bug", "no!"}, nil, true},
+		{"empty name", []string{""}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeLabels(tt.names)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NormalizeLabels = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeLabels: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeLabels = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectHasLabel(t *testing.T) {
+	project := Project{Labels: []Label{{Name: "bug", Color: "#ff0000"}}}
+	if !project.HasLabel("bug") {
+		t.Error(`HasLabel("bug") = false, want true`)
+	}
+	if project.HasLabel("Bug") {
+		t.Error(`HasLabel("Bug") = true, want false for names that aren't normalized`)
+	}
+}
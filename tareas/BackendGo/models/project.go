@@ -0,0 +1,55 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Project groups tasks into a board shared by its members
+type Project struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description" bson:"description"`
+	OwnerID     primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	Members     []ProjectMember    `json:"members" bson:"members"`
+	Workflow    *Workflow          `json:"workflow,omitempty" bson:"workflow,omitempty"`
+	Labels      []Label            `json:"labels,omitempty" bson:"labels,omitempty"` // label catalog for the project's tasks
+	CreatedAt   primitive.DateTime `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   primitive.DateTime `json:"updatedAt" bson:"updatedAt"`
+}
+
+// TaskWorkflow returns the project's custom workflow, or the default one
+func (p Project) TaskWorkflow() Workflow {
+	if p.Workflow == nil {
+		return DefaultWorkflow
+	}
+	return *p.Workflow
+}
+
+// ProjectMember is a user with access to a project's tasks
+type ProjectMember struct {
+	UserID  primitive.ObjectID `json:"userId" bson:"userId"`
+	Role    string             `json:"role" bson:"role"`
+	AddedAt primitive.DateTime `json:"addedAt" bson:"addedAt"`
+}
+
+// RoleOf returns the user's role in the project, or "" if they aren't a member
+func (p Project) RoleOf(userID primitive.ObjectID) string {
+	if userID == p.OwnerID {
+		return RoleOwner
+	}
+	for _, m := range p.Members {
+		if m.UserID == userID {
+			return m.EffectiveRole()
+		}
+	}
+	return ""
+}
+
+// EffectiveRole returns the member's role. Members added before roles existed
+// have none stored and are treated as regular members.
+func (m ProjectMember) EffectiveRole() string {
+	if m.Role == "" {
+		return RoleMember
+	}
+	return m.Role
+}
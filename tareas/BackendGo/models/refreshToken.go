@@ -0,0 +1,28 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is a long lived credential used to obtain new access tokens.
+// Only a hash of the token is stored so a database leak can't be replayed.
+// Tokens rotated from the same login share a family.
+type RefreshToken struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID  `json:"userId" bson:"userId"`
+	FamilyID   primitive.ObjectID  `json:"familyId" bson:"familyId"`
+	TokenHash  string              `json:"-" bson:"tokenHash"`
+	ExpiresAt  primitive.DateTime  `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt  primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	RevokedAt  *primitive.DateTime `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	ReplacedBy primitive.ObjectID  `json:"replacedBy,omitempty" bson:"replacedBy,omitempty"`
+}
+
+// Family returns the family of the token. Tokens issued before families
+// were tracked start their own.
+func (t RefreshToken) Family() primitive.ObjectID {
+	if t.FamilyID.IsZero() {
+		return t.ID
+	}
+	return t.FamilyID
+}
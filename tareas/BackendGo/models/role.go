@@ -0,0 +1,93 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Project roles, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+
+	// RoleAssignee is the implicit role of the person a task is assigned to.
+	// It is never stored on a project.
+	RoleAssignee = "assignee"
+)
+
+// Permissions checked by middleware.RequirePermission
+const (
+	PermTaskRead      = "task:read"
+	PermTaskCreate    = "task:create"
+	PermTaskUpdate    = "task:update" // edit any field
+	PermTaskStatus    = "task:status" // move between statuses
+	PermTaskDelete    = "task:delete"
+	PermCommentCreate = "comment:create"
+	PermCommentDelete = "comment:delete" // delete other people's comments
+
+	PermProjectRead    = "project:read"
+	PermProjectUpdate  = "project:update"
+	PermProjectMembers = "project:members"
+	PermProjectDelete  = "project:delete"
+)
+
+// rolePermissions lists what each role is allowed to do
+var rolePermissions = map[string][]string{
+	RoleOwner: {
+		PermTaskRead, PermTaskCreate, PermTaskUpdate, PermTaskStatus, PermTaskDelete,
+		PermCommentCreate, PermCommentDelete,
+		PermProjectRead, PermProjectUpdate, PermProjectMembers, PermProjectDelete,
+	},
+	RoleAdmin: {
+		PermTaskRead, PermTaskCreate, PermTaskUpdate, PermTaskStatus, PermTaskDelete,
+		PermCommentCreate, PermCommentDelete,
+		PermProjectRead, PermProjectUpdate, PermProjectMembers,
+	},
+	RoleMember: {
+		PermTaskRead, PermTaskCreate, PermTaskUpdate, PermTaskStatus,
+		PermCommentCreate,
+		PermProjectRead,
+	},
+	RoleViewer: {
+		PermTaskRead,
+		PermProjectRead,
+	},
+	RoleAssignee: {
+		PermTaskRead, PermTaskStatus,
+		PermCommentCreate,
+	},
+}
+
+// RoleAllows reports whether the role grants the permission
+func RoleAllows(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAssignableRole reports whether the role can be given to a project member.
+// Ownership can't be granted, it belongs to the project creator.
+func IsAssignableRole(role string) bool {
+	return role == RoleAdmin || role == RoleMember || role == RoleViewer
+}
+
+// RolesOf returns every role the user holds on the task: their role in the
+// task's project, or owner of a personal task they created, plus assignee.
+// project is the task's project, or the zero Project for personal tasks.
+func (t Task) RolesOf(userID primitive.ObjectID, project Project) []string {
+	var roles []string
+	if t.ProjectID == nil {
+		// Personal tasks belong to their creator
+		if t.CreatedBy == userID {
+			roles = append(roles, RoleOwner)
+		}
+	} else if role := project.RoleOf(userID); role != "" {
+		roles = append(roles, role)
+	}
+	if t.IsAssignee(userID) {
+		roles = append(roles, RoleAssignee)
+	}
+	return roles
+}
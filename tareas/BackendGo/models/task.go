@@ -1,17 +1,127 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Task struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Title       string             `json:"title" bson:"title"`
-	Description string             `json:"description" bson:"description"`
-	AssignedTo  primitive.ObjectID `json:"assignedTo" bson:"assignedTo"`
-	Status      string             `json:"status" bson:"status"`     
-	Priority    string             `json:"priority" bson:"priority"` 
-	CreatedBy   primitive.ObjectID `json:"createdBy" bson:"createdBy"`
-	CreatedAt   primitive.DateTime `json:"createdAt" bson:"createdAt"`
-	UpdatedAt   primitive.DateTime `json:"updatedAt" bson:"updatedAt"`
+	ID           primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Title        string               `json:"title" bson:"title"`
+	Description  string               `json:"description" bson:"description"`
+	AssignedTo   primitive.ObjectID   `json:"assignedTo" bson:"assignedTo"` // first assignee, kept for older clients
+	Assignees    []primitive.ObjectID `json:"assignees" bson:"assignees"`
+	Watchers     []primitive.ObjectID `json:"watchers,omitempty" bson:"watchers,omitempty"` // users following the task
+	Status       TaskStatus           `json:"status" bson:"status"`
+	Priority     TaskPriority         `json:"priority" bson:"priority"`
+	PriorityRank int                  `json:"-" bson:"priorityRank,omitempty"` // Priority.Rank(), kept for sorting
+	CreatedBy    primitive.ObjectID   `json:"createdBy" bson:"createdBy"`
+	CreatedAt    primitive.DateTime   `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    primitive.DateTime   `json:"updatedAt" bson:"updatedAt"`
+	Version      int64                `json:"version" bson:"version"` // incremented on every update
+	StartDate    *primitive.DateTime  `json:"startDate,omitempty" bson:"startDate,omitempty"`
+	DueDate      *primitive.DateTime  `json:"dueDate,omitempty" bson:"dueDate,omitempty"`
+	ProjectID    *primitive.ObjectID  `json:"projectId,omitempty" bson:"projectId,omitempty"`
+	ParentID     *primitive.ObjectID  `json:"parentId,omitempty" bson:"parentId,omitempty"` // set on subtasks
+	Checklist    []ChecklistItem      `json:"checklist,omitempty" bson:"checklist,omitempty"`
+	BlockedBy    []primitive.ObjectID `json:"blockedBy,omitempty" bson:"blockedBy,omitempty"` // tasks that must be completed first
+	Labels       []string             `json:"labels,omitempty" bson:"labels,omitempty"`
+	CompletedAt  *primitive.DateTime  `json:"completedAt,omitempty" bson:"completedAt,omitempty"` // set while the task is completed
+	Archived     bool                 `json:"archived" bson:"archived,omitempty"`                 // hidden from lists by default
+	ArchivedAt   *primitive.DateTime  `json:"archivedAt,omitempty" bson:"archivedAt,omitempty"`
+	SoftDelete   `bson:",inline"`
+}
+
+// NormalizeAssignees reconciles the assignee fields. Clients that only
+// send assignedTo get a single assignee; duplicates and empty IDs are
+// dropped, and assignedTo always mirrors the first assignee.
+func (t *Task) NormalizeAssignees() {
+	if len(t.Assignees) == 0 && !t.AssignedTo.IsZero() {
+		t.Assignees = []primitive.ObjectID{t.AssignedTo}
+	}
+	t.Assignees = uniqueIDs(t.Assignees)
+
+	t.AssignedTo = primitive.NilObjectID
+	if len(t.Assignees) > 0 {
+		t.AssignedTo = t.Assignees[0]
+	}
+}
+
+// SameProject reports whether both tasks belong to the same project, or
+// are both personal
+func (t Task) SameProject(other Task) bool {
+	if t.ProjectID == nil || other.ProjectID == nil {
+		return t.ProjectID == nil && other.ProjectID == nil
+	}
+	return *t.ProjectID == *other.ProjectID
+}
+
+// IsAssignee reports whether the user is one of the task's assignees
+func (t Task) IsAssignee(userID primitive.ObjectID) bool {
+	return t.AssignedTo == userID || containsID(t.Assignees, userID)
+}
+
+// IsWatcher reports whether the user follows the task
+func (t Task) IsWatcher(userID primitive.ObjectID) bool {
+	return containsID(t.Watchers, userID)
+}
+
+// SetStatus moves the task to a status and keeps completedAt in sync.
+// Reopening a completed task also takes it out of the archive.
+func (t *Task) SetStatus(status TaskStatus, now primitive.DateTime) {
+	if status == t.Status {
+		return
+	}
+	t.Status = status
+	if status == StatusCompleted {
+		t.CompletedAt = &now
+		return
+	}
+	t.CompletedAt = nil
+	t.Archived = false
+	t.ArchivedAt = nil
+}
+
+// IsOverdue reports whether the task is past its due date and not completed
+func (t Task) IsOverdue(now time.Time) bool {
+	return t.DueDate != nil && t.Status != StatusCompleted && t.DueDate.Time().Before(now)
+}
+
+// Result returns the task with its computed response fields filled in,
+// given the counts of its subtasks
+func (t Task) Result(subtasks SubtaskCount) TaskResult {
+	return TaskResult{Task: t, IsOverdue: t.IsOverdue(time.Now()), Progress: t.Progress(subtasks)}
+}
+
+// TaskResult is a task as returned by list and search endpoints, with the
+// fields computed for the response
+type TaskResult struct {
+	Task       `bson:",inline"`
+	Score      float64           `json:"score,omitempty" bson:"score,omitempty"`           // text search relevance
+	Highlights map[string]string `json:"highlights,omitempty" bson:"highlights,omitempty"` // matched snippets by field
+	IsOverdue  bool              `json:"isOverdue" bson:"-"`
+	Progress   *TaskProgress     `json:"progress,omitempty" bson:"-"`
+}
+
+// containsID reports whether the list contains the ID
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueIDs returns the non-empty IDs of the list without duplicates,
+// keeping their order
+func uniqueIDs(ids []primitive.ObjectID) []primitive.ObjectID {
+	unique := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if !id.IsZero() && !containsID(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	return unique
 }
@@ -0,0 +1,34 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// SoftDelete marks a task or comment as moved to the trash. Deleted items
+// are hidden everywhere except the trash until they are restored or purged.
+type SoftDelete struct {
+	DeletedAt   *primitive.DateTime `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	DeletedBy   *primitive.ObjectID `json:"deletedBy,omitempty" bson:"deletedBy,omitempty"`
+	DeletedWith *primitive.ObjectID `json:"deletedWith,omitempty" bson:"deletedWith,omitempty"` // task or comment whose deletion took this item along
+}
+
+// IsDeleted reports whether the item is in the trash
+func (d SoftDelete) IsDeleted() bool {
+	return d.DeletedAt != nil
+}
+
+// TrashedTask is a deleted task as listed in the trash
+type TrashedTask struct {
+	Task    `bson:",inline"`
+	PurgeAt primitive.DateTime `json:"purgeAt" bson:"-"` // when it will be deleted for good
+}
+
+// TrashedComment is a deleted comment as listed in the trash
+type TrashedComment struct {
+	Comment `bson:",inline"`
+	PurgeAt primitive.DateTime `json:"purgeAt" bson:"-"`
+}
+
+// Trash lists the items a user deleted
+type Trash struct {
+	Tasks    []TrashedTask    `json:"tasks"`
+	Comments []TrashedComment `json:"comments"`
+}
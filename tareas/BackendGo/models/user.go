@@ -4,9 +4,44 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Default role given to every registered user
+const RoleUser = "user"
+
 type User struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name	  string             `json:"name" bson:"name"`
 	Email     string             `json:"email" bson:"email"`
-	Password  string             `json:"password" bson:"password"`
-}
\ No newline at end of file
+	Password  string             `json:"-" bson:"password"`
+	Avatar    string             `json:"avatar" bson:"avatar,omitempty"`
+	Role      string             `json:"role" bson:"role,omitempty"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt,omitempty"`
+	UpdatedAt primitive.DateTime `json:"updatedAt" bson:"updatedAt,omitempty"`
+}
+
+// PublicUser is the representation of a user returned by the API
+type PublicUser struct {
+	ID        primitive.ObjectID `json:"id"`
+	Name      string             `json:"name"`
+	Email     string             `json:"email"`
+	Avatar    string             `json:"avatar"`
+	Role      string             `json:"role"`
+	CreatedAt primitive.DateTime `json:"createdAt"`
+	UpdatedAt primitive.DateTime `json:"updatedAt"`
+}
+
+// Public returns the user without any credential fields
+func (u User) Public() PublicUser {
+	role := u.Role
+	if role == "" {
+		role = RoleUser
+	}
+	return PublicUser{
+		ID:        u.ID,
+		Name:      u.Name,
+		Email:     u.Email,
+		Avatar:    u.Avatar,
+		Role:      role,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TaskStatus is the workflow state of a task
+type TaskStatus string
+
+// Statuses of the default workflow
+const (
+	StatusPending    TaskStatus = "pendiente"
+	StatusInProgress TaskStatus = "en_progreso"
+	StatusCompleted  TaskStatus = "completada"
+)
+
+// WellFormed reports whether the status is a valid status name. Whether it
+// belongs to a given workflow is checked by Workflow.HasStatus.
+func (s TaskStatus) WellFormed() bool {
+	return statusNamePattern.MatchString(string(s))
+}
+
+// TaskPriority is how urgent a task is
+type TaskPriority string
+
+const (
+	PriorityLow    TaskPriority = "baja"
+	PriorityMedium TaskPriority = "media"
+	PriorityHigh   TaskPriority = "alta"
+)
+
+// Priorities lists the valid priorities from lowest to highest
+var Priorities = []TaskPriority{PriorityLow, PriorityMedium, PriorityHigh}
+
+// Valid reports whether p is a known priority
+func (p TaskPriority) Valid() bool {
+	for _, priority := range Priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// Rank orders priorities from 1 (lowest) up, and is 0 for unknown ones.
+// Tasks store it so priority sorts can use an index.
+func (p TaskPriority) Rank() int {
+	for i, priority := range Priorities {
+		if p == priority {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Workflow defines the statuses a project's tasks can be in and which moves
+// between them are allowed. Every workflow contains StatusCompleted, which
+// marks a task as done; the first status is given to new tasks.
+type Workflow struct {
+	Statuses    []TaskStatus                `json:"statuses" bson:"statuses"`
+	Transitions map[TaskStatus][]TaskStatus `json:"transitions,omitempty" bson:"transitions,omitempty"` // empty allows any move
+}
+
+// DefaultWorkflow is used by tasks outside a project and by projects without
+// a custom workflow. Any status can be reached from any other.
+var DefaultWorkflow = Workflow{
+	Statuses: []TaskStatus{StatusPending, StatusInProgress, StatusCompleted},
+}
+
+var statusNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// Initial returns the status given to new tasks
+func (w Workflow) Initial() TaskStatus {
+	return w.Statuses[0]
+}
+
+// HasStatus reports whether the status belongs to the workflow
+func (w Workflow) HasStatus(status TaskStatus) bool {
+	for _, s := range w.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransition reports whether a task can move from one status to another
+func (w Workflow) CanTransition(from, to TaskStatus) bool {
+	if from == to || len(w.Transitions) == 0 {
+		return true
+	}
+	for _, next := range w.Transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the workflow is well formed
+func (w Workflow) Validate() error {
+	if len(w.Statuses) == 0 {
+		return errors.New("Workflow needs at least one status")
+	}
+
+	seen := map[TaskStatus]bool{}
+	for _, status := range w.Statuses {
+		if !status.WellFormed() {
+			return fmt.Errorf("Invalid status name %q. Use lowercase letters, digits and underscores", status)
+		}
+		if seen[status] {
+			return fmt.Errorf("Duplicated status %q", status)
+		}
+		seen[status] = true
+	}
+
+	if !seen[StatusCompleted] {
+		return fmt.Errorf("Workflow must include the %q status", StatusCompleted)
+	}
+
+	for from, targets := range w.Transitions {
+		if !seen[from] {
+			return fmt.Errorf("Transition from unknown status %q", from)
+		}
+		for _, to := range targets {
+			if !seen[to] {
+				return fmt.Errorf("Transition to unknown status %q", to)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateStatus checks that the status belongs to the workflow
+func (w Workflow) ValidateStatus(status TaskStatus) error {
+	if !w.HasStatus(status) {
+		return fmt.Errorf("Invalid status. Valid values: %s", w.statusList())
+	}
+	return nil
+}
+
+// ValidateTaskState checks a task's status and priority against the workflow.
+// It is the single place where these values are validated.
+func (w Workflow) ValidateTaskState(status TaskStatus, priority TaskPriority) error {
+	if err := w.ValidateStatus(status); err != nil {
+		return err
+	}
+	if !priority.Valid() {
+		return errors.New("Invalid priority. Valid values: baja, media, alta")
+	}
+	return nil
+}
+
+// statusList returns the workflow statuses as a comma separated list
+func (w Workflow) statusList() string {
+	names := make([]string, len(w.Statuses))
+	for i, s := range w.Statuses {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
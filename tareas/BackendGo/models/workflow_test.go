@@ -0,0 +1,132 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorkflowValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow Workflow
+		wantErr  string // empty when the workflow is valid
+	}{
+		{"default", DefaultWorkflow, ""},
+		{"custom with transitions", Workflow{
+			Statuses: []TaskStatus{"backlog", "review_2", StatusCompleted},
+			Transitions: map[TaskStatus][]TaskStatus{
+				"backlog":  {"review_2"},
+				"review_2": {"backlog", StatusCompleted},
+			},
+		}, ""},
+		{"only completed", Workflow{Statuses: []TaskStatus{StatusCompleted}}, ""},
+		{"no statuses", Workflow{}, "at least one status"},
+		{"missing completed", Workflow{Statuses: []TaskStatus{"todo", "done"}}, `"completada"`},
+		{"duplicated status", Workflow{Statuses: []TaskStatus{"todo", "todo", StatusCompleted}}, "Duplicated"},
+		{"uppercase status", Workflow{Statuses: []TaskStatus{"Todo", StatusCompleted}}, "Invalid status name"},
+		{"status starting with a digit", Workflow{Statuses: []TaskStatus{"1st", StatusCompleted}}, "Invalid status name"},
+		{"status with spaces", Workflow{Statuses: []TaskStatus{"en curso", StatusCompleted}}, "Invalid status name"},
+		{"status too long", Workflow{Statuses: []TaskStatus{TaskStatus("a" + strings.Repeat("b", 32)), StatusCompleted}}, "Invalid status name"},
+		{"transition from unknown", Workflow{
+			Statuses:    []TaskStatus{"todo", StatusCompleted},
+			Transitions: map[TaskStatus][]TaskStatus{"doing": {StatusCompleted}},
+		}, "from unknown"},
+		{"transition to unknown", Workflow{
+			Statuses:    []TaskStatus{"todo", StatusCompleted},
+			Transitions: map[TaskStatus][]TaskStatus{"todo": {"doing"}},
+		}, "to unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.workflow.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkflowCanTransition(t *testing.T) {
+	restricted := Workflow{
+		Statuses: []TaskStatus{"todo", "doing", StatusCompleted},
+		Transitions: map[TaskStatus][]TaskStatus{
+			"todo":  {"doing"},
+			"doing": {"todo", StatusCompleted},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		workflow Workflow
+		from, to TaskStatus
+		want     bool
+	}{
+		{"no transitions allow any move", DefaultWorkflow, StatusCompleted, StatusPending, true},
+		{"listed move", restricted, "todo", "doing", true},
+		{"unlisted move", restricted, "todo", StatusCompleted, false},
+		{"move back", restricted, "doing", "todo", true},
+		{"status without transitions", restricted, StatusCompleted, "todo", false},
+		{"same status", restricted, StatusCompleted, StatusCompleted, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.workflow.CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflowValidateTaskState(t *testing.T) {
+	workflow := Workflow{Statuses: []TaskStatus{"todo", StatusCompleted}}
+
+	tests := []struct {
+		status   TaskStatus
+		priority TaskPriority
+		wantErr  string
+	}{
+		{"todo", PriorityHigh, ""},
+		{StatusCompleted, PriorityLow, ""},
+		{StatusPending, PriorityMedium, "Valid values: todo, completada"},
+		{"", PriorityMedium, "Invalid status"},
+		{"todo", "urgente", "Invalid priority"},
+		{"todo", "", "Invalid priority"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status)+"/"+string(tt.priority), func(t *testing.T) {
+			err := workflow.ValidateTaskState(tt.status, tt.priority)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateTaskState: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateTaskState = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := workflow.ValidateStatus("todo"); err != nil {
+		t.Errorf("ValidateStatus: %v", err)
+	}
+	if err := workflow.ValidateStatus(StatusInProgress); err == nil {
+		t.Errorf("ValidateStatus(%q) succeeded, want an error", StatusInProgress)
+	}
+}
+
+func TestWorkflowInitial(t *testing.T) {
+	if got := DefaultWorkflow.Initial(); got != StatusPending {
+		t.Errorf("DefaultWorkflow.Initial() = %q, want %q", got, StatusPending)
+	}
+	custom := Workflow{Statuses: []TaskStatus{"backlog", StatusCompleted}}
+	if got := custom.Initial(); got != "backlog" {
+		t.Errorf("Initial() = %q, want %q", got, "backlog")
+	}
+}
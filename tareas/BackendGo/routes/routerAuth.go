@@ -11,8 +11,11 @@ func RoutesAuth(router *gin.Engine) {
 
 	router.POST("/auth/register", controllers.CreateUser)
 	router.POST("/auth/login", controllers.LoginUser)
+	router.POST("/auth/refresh", controllers.RefreshToken)
+	router.POST("/auth/logout", controllers.LogoutUser)
 
 	// Protected routes
 	router.GET("/user/me", middleware.AuthMiddleware(), controllers.UserMe)
+	router.DELETE("/user/me", middleware.AuthMiddleware(), controllers.DeleteUserMe)
 	router.GET("/users", middleware.AuthMiddleware(), controllers.GetAllUsers)
 }
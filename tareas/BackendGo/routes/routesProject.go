@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"go-template/controllers"
+	"go-template/middleware"
+	"go-template/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RoutesProject(router *gin.Engine) {
+	// routes for projects
+	router.POST("/projects", middleware.AuthMiddleware(), controllers.CreateProject)
+	router.GET("/projects", middleware.AuthMiddleware(), controllers.GetProjects)
+	router.GET("/projects/:id", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectRead), controllers.GetProjectByID)
+	router.PATCH("/projects/:id", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectUpdate), controllers.UpdateProject)
+	router.DELETE("/projects/:id", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectDelete), controllers.DeleteProject)
+	router.PUT("/projects/:id/workflow", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectUpdate), controllers.UpdateProjectWorkflow)
+	router.GET("/projects/:id/dependencies", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectRead), controllers.GetProjectDependencyGraph)
+	router.GET("/projects/:id/tasks", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectRead), controllers.GetProjectTasks)
+
+	// routes for the project label catalog
+	router.GET("/projects/:id/labels", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectRead), controllers.GetProjectLabels)
+	router.POST("/projects/:id/labels", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectUpdate), controllers.CreateProjectLabel)
+	router.PATCH("/projects/:id/labels/:name", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectUpdate), controllers.UpdateProjectLabel)
+	router.DELETE("/projects/:id/labels/:name", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectUpdate), controllers.DeleteProjectLabel)
+
+	// routes for project membership
+	router.POST("/projects/:id/members", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectMembers), controllers.AddProjectMember)
+	router.PATCH("/projects/:id/members/:userId", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectMembers), controllers.UpdateProjectMemberRole)
+	router.DELETE("/projects/:id/members/:userId", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermProjectMembers), controllers.RemoveProjectMember)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"go-template/controllers"
 	"go-template/middleware"
+	"go-template/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,12 +12,46 @@ func RegisterRoutes(router *gin.Engine) {
 	// routes for tasks
 	router.POST("/tasks", middleware.AuthMiddleware(), controllers.CreateTask)
 	router.GET("/tasks", middleware.AuthMiddleware(), controllers.GetTasks)
-	router.GET("/tasks/:id", middleware.AuthMiddleware(), controllers.GetTaskByID)
-	router.PUT("/tasks/:id", middleware.AuthMiddleware(), controllers.UpdateTaskStatus)
-	router.DELETE("/tasks/:id", middleware.AuthMiddleware(), controllers.DeleteTask)
+	router.GET("/tasks/:id", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskRead), controllers.GetTaskByID)
+	router.PUT("/tasks/:id", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskStatus), controllers.UpdateTaskStatus)
+	router.PATCH("/tasks/:id", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskUpdate), controllers.UpdateTask)
+	router.DELETE("/tasks/:id", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskDelete), controllers.DeleteTask)
+
+	// archive: completed tasks hidden from lists
+	router.POST("/tasks/:id/archive", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskUpdate), controllers.ArchiveTask)
+	router.POST("/tasks/:id/unarchive", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskUpdate), controllers.UnarchiveTask)
+
+	// trash: deleted tasks and comments can be restored until they are purged
+	router.GET("/trash", middleware.AuthMiddleware(), controllers.GetTrash)
+	router.POST("/tasks/:id/restore", middleware.AuthMiddleware(), middleware.AllowDeleted(), middleware.RequirePermission(models.PermTaskDelete), controllers.RestoreTask)
+	router.POST("/comments/:commentId/restore", middleware.AuthMiddleware(), middleware.AllowDeleted(), middleware.RequirePermission(models.PermTaskRead), controllers.RestoreComment)
+
+	// routes for subtasks and checklists
+	router.POST("/tasks/:id/subtasks", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskCreate), controllers.CreateSubtask)
+	router.GET("/tasks/:id/subtasks", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskRead), controllers.GetSubtasks)
+	router.POST("/tasks/:id/checklist", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskUpdate), controllers.AddChecklistItem)
+	router.PATCH("/tasks/:id/checklist/:itemId", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskStatus), controllers.ToggleChecklistItem)
+	router.DELETE("/tasks/:id/checklist/:itemId", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskUpdate), controllers.RemoveChecklistItem)
+
+	// routes for dependencies between tasks
+	router.GET("/tasks/:id/dependencies", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskRead), controllers.GetTaskDependencies)
+	router.POST("/tasks/:id/dependencies", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskUpdate), controllers.AddTaskDependency)
+	router.DELETE("/tasks/:id/dependencies/:blockerId", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskUpdate), controllers.RemoveTaskDependency)
+
+	// routes for task watchers
+	router.POST("/tasks/:id/watchers", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskRead), controllers.AddTaskWatcher)
+	router.DELETE("/tasks/:id/watchers/:userId", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskRead), controllers.RemoveTaskWatcher)
+
+	// history of changes to a task and its comments
+	router.GET("/tasks/:id/activity", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskRead), controllers.GetTaskActivity)
+
+	// full-text search over tasks and comments
+	router.GET("/search", middleware.AuthMiddleware(), controllers.SearchAll)
 
 	// routes for comments 
-	router.POST("/tasks/:id/comments", middleware.AuthMiddleware(), controllers.CreateComment)
-	router.GET("/tasks/:id/comments", middleware.AuthMiddleware(), controllers.GetCommentsByTask)
-	router.DELETE("/comments/:commentId", middleware.AuthMiddleware(), controllers.DeleteComment)
+	router.POST("/tasks/:id/comments", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermCommentCreate), controllers.CreateComment)
+	router.GET("/tasks/:id/comments", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermTaskRead), controllers.GetCommentsByTask)
+	router.PATCH("/comments/:commentId", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermCommentCreate), controllers.UpdateComment)
+	router.DELETE("/comments/:commentId", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermCommentCreate), controllers.DeleteComment)
+	router.POST("/comments/:commentId/reactions", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermCommentCreate), controllers.ToggleCommentReaction)
 }
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"go-template/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecordActivities adds entries to the history of tasks. Pass a session
+// context to record them in the same transaction as the change.
+func RecordActivities(ctx context.Context, activities []models.Activity) error {
+	if len(activities) == 0 {
+		return nil
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	docs := make([]interface{}, 0, len(activities))
+	for _, activity := range activities {
+		activity.ID = primitive.NewObjectID()
+		if activity.CreatedAt == 0 {
+			activity.CreatedAt = now
+		}
+		docs = append(docs, activity)
+	}
+
+	_, err := DB.Collection("activities").InsertMany(ctx, docs)
+	return err
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"go-template/models"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// autoArchiveInterval is how often completed tasks are checked for archiving
+const autoArchiveInterval = time.Hour
+
+// StartAutoArchive archives the tasks completed more than days ago, once at
+// startup and then every hour. A value of 0 disables it.
+func StartAutoArchive(days int) {
+	if days <= 0 {
+		return
+	}
+	after := time.Duration(days) * 24 * time.Hour
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), autoArchiveInterval)
+			archived, err := ArchiveCompletedBefore(ctx, time.Now().Add(-after))
+			if err != nil {
+				log.Println("Auto-archive failed:", err)
+			} else if archived > 0 {
+				log.Printf("Auto-archived %d completed tasks", archived)
+			}
+			cancel()
+			time.Sleep(autoArchiveInterval)
+		}
+	}()
+}
+
+// ArchiveCompletedBefore archives the tasks completed before cutoff and
+// returns how many were archived. Tasks completed before completedAt was
+// recorded are judged by their last update. Tasks are archived in batches,
+// each one in a transaction that also records it in their history.
+func ArchiveCompletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	before := primitive.NewDateTimeFromTime(cutoff)
+	filter := bson.M{
+		"status":    models.StatusCompleted,
+		"archived":  bson.M{"$ne": true},
+		"deletedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"completedAt": bson.M{"$lt": before}},
+			bson.M{"completedAt": bson.M{"$exists": false}, "updatedAt": bson.M{"$lt": before}},
+		},
+	}
+	tasks := DB.Collection("tasks")
+
+	var archived int64
+	for {
+		var batch int
+		err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+			cursor, err := tasks.Find(sc, filter, options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(purgeBatchSize))
+			if err != nil {
+				return err
+			}
+			var found []models.Task
+			if err := cursor.All(sc, &found); err != nil {
+				return err
+			}
+			batch = len(found)
+			if batch == 0 {
+				return nil
+			}
+
+			now := primitive.NewDateTimeFromTime(time.Now())
+			ids := make([]primitive.ObjectID, 0, len(found))
+			activities := make([]models.Activity, 0, len(found))
+			for _, task := range found {
+				ids = append(ids, task.ID)
+				activities = append(activities, models.Activity{
+					TaskID: task.ID,
+					Action: models.ActivityTaskUpdated,
+					Changes: []models.FieldChange{
+						{Field: "archived", Before: false, After: true},
+						{Field: "archivedAt", Before: nil, After: now},
+					},
+					CreatedAt: now,
+				})
+			}
+
+			_, err = tasks.UpdateMany(sc,
+				bson.M{"_id": bson.M{"$in": ids}},
+				bson.M{"$set": bson.M{"archived": true, "archivedAt": now}, "$inc": bson.M{"version": 1}},
+			)
+			if err != nil {
+				return err
+			}
+			return RecordActivities(sc, activities)
+		})
+		if err != nil {
+			return archived, err
+		}
+		if batch == 0 {
+			return archived, nil
+		}
+		archived += int64(batch)
+	}
+}
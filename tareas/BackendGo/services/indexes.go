@@ -0,0 +1,93 @@
+package services
+
+import (
+    "context"
+    "log"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the application relies on
+func EnsureIndexes() {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    // Refresh tokens are looked up by hash, revoked by user or family and
+    // expire on their own
+    _, err := DB.Collection("refresh_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+        {Keys: bson.D{{Key: "userId", Value: 1}}},
+        {Keys: bson.D{{Key: "familyId", Value: 1}}},
+        {Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+    })
+    if err != nil {
+        log.Fatal("Failed to create refresh_tokens indexes:", err)
+    }
+
+    // Task lists are filtered by creator, assignee or project and paged by
+    // date, with the project and assignee lists also sorted by priority or
+    // due date
+    _, err = DB.Collection("tasks").Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{{Key: "createdBy", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+        {Keys: bson.D{{Key: "assignees", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+        {Keys: bson.D{{Key: "watchers", Value: 1}}},
+        {Keys: bson.D{{Key: "dueDate", Value: 1}}},
+        {Keys: bson.D{{Key: "status", Value: 1}, {Key: "completedAt", Value: 1}}},
+        {Keys: bson.D{{Key: "projectId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+        {Keys: bson.D{{Key: "projectId", Value: 1}, {Key: "priorityRank", Value: -1}, {Key: "_id", Value: -1}}},
+        {Keys: bson.D{{Key: "projectId", Value: 1}, {Key: "dueDate", Value: 1}, {Key: "_id", Value: 1}}},
+        {Keys: bson.D{{Key: "assignees", Value: 1}, {Key: "priorityRank", Value: -1}, {Key: "_id", Value: -1}}},
+        {Keys: bson.D{{Key: "assignees", Value: 1}, {Key: "dueDate", Value: 1}, {Key: "_id", Value: 1}}},
+        {Keys: bson.D{{Key: "parentId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+        {Keys: bson.D{{Key: "blockedBy", Value: 1}}},
+        {Keys: bson.D{{Key: "deletedBy", Value: 1}, {Key: "deletedAt", Value: -1}}, Options: options.Index().SetSparse(true)},
+        {Keys: bson.D{{Key: "deletedAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+        {Keys: bson.D{{Key: "deletedWith", Value: 1}}, Options: options.Index().SetSparse(true)},
+        {Keys: bson.D{{Key: "labels", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+        {
+            Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+            Options: options.Index().
+                SetName("tasks_text").
+                SetWeights(bson.D{{Key: "title", Value: 5}, {Key: "description", Value: 1}}).
+                SetDefaultLanguage("spanish"),
+        },
+    })
+    if err != nil {
+        log.Fatal("Failed to create tasks indexes:", err)
+    }
+
+    // Projects are looked up by member
+    _, err = DB.Collection("projects").Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "members.userId", Value: 1}},
+    })
+    if err != nil {
+        log.Fatal("Failed to create projects indexes:", err)
+    }
+
+    // Comments are listed per task, grouped in threads and searched by text
+    _, err = DB.Collection("comments").Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{{Key: "taskId", Value: 1}, {Key: "createdAt", Value: 1}}},
+        {Keys: bson.D{{Key: "parentId", Value: 1}}, Options: options.Index().SetSparse(true)},
+        {Keys: bson.D{{Key: "deletedBy", Value: 1}, {Key: "deletedAt", Value: -1}}, Options: options.Index().SetSparse(true)},
+        {Keys: bson.D{{Key: "deletedAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+        {Keys: bson.D{{Key: "deletedWith", Value: 1}}, Options: options.Index().SetSparse(true)},
+        {
+            Keys:    bson.D{{Key: "text", Value: "text"}},
+            Options: options.Index().SetName("comments_text").SetDefaultLanguage("spanish"),
+        },
+    })
+    if err != nil {
+        log.Fatal("Failed to create comments indexes:", err)
+    }
+
+    // Task activity is paged newest first
+    _, err = DB.Collection("activities").Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "taskId", Value: 1}, {Key: "_id", Value: -1}},
+    })
+    if err != nil {
+        log.Fatal("Failed to create activities indexes:", err)
+    }
+}
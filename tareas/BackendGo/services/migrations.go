@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"go-template/models"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MigrateData upgrades documents written by older versions of the API. Each
+// step only touches documents still in the old shape, so it is safe to run
+// on every start.
+func MigrateData() {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Tasks used to have a single assignedTo; queries now use assignees
+	result, err := DB.Collection("tasks").UpdateMany(ctx,
+		bson.M{"assignees": bson.M{"$exists": false}, "assignedTo": bson.M{"$exists": true}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"assignees": bson.A{"$assignedTo"}}}}},
+	)
+	if err != nil {
+		log.Fatal("Failed to migrate task assignees:", err)
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Migrated assignees of %d tasks", result.ModifiedCount)
+	}
+
+	// Priority sorts use the stored priorityRank
+	branches := bson.A{}
+	for _, priority := range models.Priorities {
+		branches = append(branches, bson.M{"case": bson.M{"$eq": bson.A{"$priority", priority}}, "then": priority.Rank()})
+	}
+	result, err = DB.Collection("tasks").UpdateMany(ctx,
+		bson.M{"priorityRank": bson.M{"$exists": false}, "priority": bson.M{"$in": models.Priorities}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"priorityRank": bson.M{"$switch": bson.M{"branches": branches}}}}}},
+	)
+	if err != nil {
+		log.Fatal("Failed to migrate task priority ranks:", err)
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Migrated priority ranks of %d tasks", result.ModifiedCount)
+	}
+}
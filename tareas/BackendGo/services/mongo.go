@@ -5,21 +5,59 @@ import (
     "fmt"
     "log"
     "time"
+    "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/mongo"
     "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var Client *mongo.Client
 
-func InitMongo() {
+// DB is the application database, selected from the configuration
+var DB *mongo.Database
+
+func InitMongo(uri, dbName string) {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
     var err error
-    Client, err = mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+    Client, err = mongo.Connect(ctx, options.Client().ApplyURI(uri))
     if err != nil {
         log.Fatal("MongoDB connection error:", err)
     }
 
+    // Connect is lazy, ping so a bad URI fails at startup
+    if err = Client.Ping(ctx, nil); err != nil {
+        log.Fatal("MongoDB ping error:", err)
+    }
+
+    DB = Client.Database(dbName)
+
     fmt.Println("Connected to MongoDB")
-}
\ No newline at end of file
+}
+
+// WithTransaction runs fn in a MongoDB transaction, committing it if fn
+// succeeds and aborting it otherwise. Transactions need a replica set.
+func WithTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+    session, err := Client.StartSession()
+    if err != nil {
+        return err
+    }
+    defer session.EndSession(ctx)
+
+    _, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+        return nil, fn(sc)
+    })
+    return err
+}
+
+// LockInTransaction writes to the lock document named key so that concurrent
+// transactions taking the same lock conflict: one of them is aborted and
+// retried by WithTransaction once the other commits, so it sees its writes.
+func LockInTransaction(sc mongo.SessionContext, key string) error {
+    _, err := DB.Collection("locks").UpdateOne(sc,
+        bson.M{"_id": key},
+        bson.M{"$inc": bson.M{"n": 1}},
+        options.Update().SetUpsert(true),
+    )
+    return err
+}
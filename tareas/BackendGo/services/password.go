@@ -0,0 +1,48 @@
+package services
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordCost is the bcrypt work factor used for new password hashes
+const PasswordCost = 12
+
+// MaxPasswordLength is the longest password in bytes bcrypt can hash
+const MaxPasswordLength = 72
+
+// HashPassword hashes a plain text password with bcrypt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// isBcryptHash reports whether the stored value looks like a bcrypt hash
+func isBcryptHash(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+// CheckPassword compares a candidate password against the stored value.
+// Legacy records saved before hashing was introduced hold the password in
+// plain text; they are compared in constant time and reported as needing a
+// rehash, as are bcrypt hashes created with a lower cost than PasswordCost.
+func CheckPassword(stored, candidate string) (ok bool, needsRehash bool) {
+	if !isBcryptHash(stored) {
+		match := subtle.ConstantTimeCompare([]byte(stored), []byte(candidate)) == 1
+		return match, match
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(candidate)); err != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(stored))
+	return true, err == nil && cost < PasswordCost
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !isBcryptHash(hash) {
+		t.Fatalf("HashPassword returned %q, want a bcrypt hash", hash)
+	}
+	if cost, _ := bcrypt.Cost([]byte(hash)); cost != PasswordCost {
+		t.Errorf("cost = %d, want %d", cost, PasswordCost)
+	}
+
+	if _, err := HashPassword(strings.Repeat("a", MaxPasswordLength)); err != nil {
+		t.Errorf("HashPassword with %d bytes: %v", MaxPasswordLength, err)
+	}
+	if _, err := HashPassword(strings.Repeat("a", MaxPasswordLength+1)); err == nil {
+		t.Errorf("HashPassword with %d bytes succeeded, want an error", MaxPasswordLength+1)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	current, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	weak, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		stored      string
+		candidate   string
+		ok          bool
+		needsRehash bool
+	}{
+		{"current hash", current, "secret", true, false},
+		{"current hash, wrong password", current, "Secret", false, false},
+		{"lower cost hash", string(weak), "secret", true, true},
+		{"lower cost hash, wrong password", string(weak), "other", false, false},
+		{"plain text", "secret", "secret", true, true},
+		{"plain text, wrong password", "secret", "secret2", false, false},
+		{"plain text, empty candidate", "secret", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash := CheckPassword(tt.stored, tt.candidate)
+			if ok != tt.ok || needsRehash != tt.needsRehash {
+				t.Errorf("CheckPassword = (%v, %v), want (%v, %v)", ok, needsRehash, tt.ok, tt.needsRehash)
+			}
+		})
+	}
+}
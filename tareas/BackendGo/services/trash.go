@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"go-template/models"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// purgeBatchSize is how many tasks are purged per transaction
+const purgeBatchSize = 500
+
+// trashRetention is how long deleted items stay in the trash
+var trashRetention = 30 * 24 * time.Hour
+
+// StartTrashPurge permanently deletes the items that have been in the trash
+// longer than retention, once at startup and then every interval
+func StartTrashPurge(retention, interval time.Duration) {
+	trashRetention = retention
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := PurgeTrash(ctx, time.Now().Add(-retention)); err != nil {
+				log.Println("Trash purge failed:", err)
+			}
+			cancel()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// PurgeTime returns when an item deleted at deletedAt will be purged
+func PurgeTime(deletedAt primitive.DateTime) primitive.DateTime {
+	return primitive.NewDateTimeFromTime(deletedAt.Time().Add(trashRetention))
+}
+
+// PurgeTrash permanently deletes the tasks and comments deleted before cutoff
+func PurgeTrash(ctx context.Context, cutoff time.Time) error {
+	expired := bson.M{"deletedAt": bson.M{"$lt": primitive.NewDateTimeFromTime(cutoff)}}
+
+	if err := PurgeTasks(ctx, expired); err != nil {
+		return err
+	}
+
+	_, err := DB.Collection("comments").DeleteMany(ctx, expired)
+	return err
+}
+
+// PurgeTasks permanently deletes the matching tasks together with their
+// comments and activity, and removes them from the dependencies of other
+// tasks. Tasks are deleted in batches, each one in its own transaction.
+func PurgeTasks(ctx context.Context, filter bson.M) error {
+	tasks := DB.Collection("tasks")
+
+	for {
+		cursor, err := tasks.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(purgeBatchSize))
+		if err != nil {
+			return err
+		}
+		var batch []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &batch); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(batch))
+		for _, task := range batch {
+			ids = append(ids, task.ID)
+		}
+
+		err = WithTransaction(ctx, func(sc mongo.SessionContext) error {
+			if _, err := tasks.DeleteMany(sc, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+				return err
+			}
+			if _, err := DB.Collection("comments").DeleteMany(sc, bson.M{"taskId": bson.M{"$in": ids}}); err != nil {
+				return err
+			}
+			if _, err := DB.Collection("activities").DeleteMany(sc, bson.M{"taskId": bson.M{"$in": ids}}); err != nil {
+				return err
+			}
+			return unlinkPurgedTasks(sc, ids)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unlinkPurgedTasks removes purged tasks from the dependencies of the
+// remaining tasks and records the change in their history
+func unlinkPurgedTasks(sc mongo.SessionContext, ids []primitive.ObjectID) error {
+	tasks := DB.Collection("tasks")
+	filter := bson.M{"blockedBy": bson.M{"$in": ids}}
+
+	cursor, err := tasks.Find(sc, filter, options.Find().SetProjection(bson.M{"blockedBy": 1}))
+	if err != nil {
+		return err
+	}
+	var dependents []models.Task
+	if err := cursor.All(sc, &dependents); err != nil {
+		return err
+	}
+	if len(dependents) == 0 {
+		return nil
+	}
+
+	purged := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		purged[id] = true
+	}
+	activities := make([]models.Activity, 0, len(dependents))
+	for _, task := range dependents {
+		remaining := []primitive.ObjectID{}
+		for _, id := range task.BlockedBy {
+			if !purged[id] {
+				remaining = append(remaining, id)
+			}
+		}
+		activities = append(activities, models.Activity{
+			TaskID:  task.ID,
+			Action:  models.ActivityTaskUpdated,
+			Changes: []models.FieldChange{{Field: "blockedBy", Before: task.BlockedBy, After: remaining}},
+		})
+	}
+
+	_, err = tasks.UpdateMany(sc, filter,
+		bson.M{"$pull": bson.M{"blockedBy": bson.M{"$in": ids}}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return err
+	}
+	return RecordActivities(sc, activities)
+}